@@ -0,0 +1,93 @@
+package gmeter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+type CheckConfig struct {
+	Status     string
+	BodyRegex  string
+	JsonPaths  []string
+	Headers    []string
+	MaxLatency time.Duration
+}
+
+type check struct {
+	name  string
+	check func(res *Response) bool
+}
+
+// Checker runs the configured assertions against every response.
+type Checker struct {
+	checks []*check
+}
+
+func NewChecker(config *CheckConfig) (*Checker, error) {
+	checker := &Checker{}
+	if len(config.Status) != 0 {
+		rule, err := ParseStatusRule(config.Status)
+		if err != nil {
+			return nil, err
+		}
+		checker.add(fmt.Sprintf("status %v", config.Status), func(res *Response) bool {
+			return rule.Match(res.StatusCode)
+		})
+	}
+	if len(config.BodyRegex) != 0 {
+		re, err := regexp.Compile(config.BodyRegex)
+		if err != nil {
+			return nil, err
+		}
+		checker.add(fmt.Sprintf("body =~ %v", config.BodyRegex), func(res *Response) bool {
+			return re.MatchString(res.BodyString())
+		})
+	}
+	for _, item := range config.JsonPaths {
+		path, expected, equals := strings.Cut(item, "=")
+		path = strings.TrimSpace(path)
+		if len(path) == 0 {
+			return nil, fmt.Errorf("invalid json check %q, want path or path=value", item)
+		}
+		checker.add(fmt.Sprintf("json %v", item), func(res *Response) bool {
+			value, ok := lookupJsonPath(res.Body, path)
+			return ok && (!equals || jsonString(value) == expected)
+		})
+	}
+	for _, item := range config.Headers {
+		key, expected, equals := strings.Cut(item, ":")
+		key = strings.TrimSpace(key)
+		expected = strings.TrimSpace(expected)
+		if len(key) == 0 {
+			return nil, fmt.Errorf("invalid header check %q, want name or name: value", item)
+		}
+		checker.add(fmt.Sprintf("header %v", item), func(res *Response) bool {
+			values := res.Header.Values(key)
+			return len(values) != 0 && (!equals || slices.Contains(values, expected))
+		})
+	}
+	if config.MaxLatency > 0 {
+		checker.add(fmt.Sprintf("latency <= %v", config.MaxLatency), func(res *Response) bool {
+			return res.Cost <= config.MaxLatency
+		})
+	}
+	return checker, nil
+}
+
+func (checker *Checker) add(name string, f func(res *Response) bool) {
+	checker.checks = append(checker.checks, &check{name: name, check: f})
+}
+
+// Check returns the names of the checks res fails.
+func (checker *Checker) Check(res *Response) []string {
+	var failures []string
+	for _, check := range checker.checks {
+		if !check.check(res) {
+			failures = append(failures, check.name)
+		}
+	}
+	return failures
+}
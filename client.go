@@ -1,22 +1,43 @@
 package gmeter
 
 import (
+	"context"
 	"crypto/tls"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"sync/atomic"
 	"time"
 )
 
 type Client struct {
-	id     int
-	client *http.Client
-	config *ClientConfig
-	meter  *Meter
+	id       int
+	client   *http.Client
+	config   *ClientConfig
+	meter    *Meter
+	deadline time.Time
+	quit     chan struct{}
+	observe  func(*Response)
+	inflight *atomic.Int64
+	checker  *Checker
+	row      map[string]string
+	session  *session
+	// limit bounds the requests the client takes, 0 for no bound.
+	limit int
 }
 
-func NewClient(id int, config *ClientConfig) (*Client, error) {
+func NewClient(id int, config *ClientConfig, meterConfig *MeterConfig) (*Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   config.TLSTimeout,
+		ResponseHeaderTimeout: config.HeaderTimeout,
+		IdleConnTimeout:       config.IdleTimeout,
 	}
 	if len(config.Proxy) != 0 {
 		if proxyUrl, err := url.Parse(config.Proxy); err != nil {
@@ -25,13 +46,26 @@ func NewClient(id int, config *ClientConfig) (*Client, error) {
 			transport.Proxy = http.ProxyURL(proxyUrl)
 		}
 	}
+	checker, err := NewChecker(&config.Checks)
+	if err != nil {
+		return nil, err
+	}
+	var clientSession *session
+	if config.Session.enabled() {
+		if clientSession, err = newSession(&config.Session); err != nil {
+			return nil, err
+		}
+	}
 	return &Client{
-		id: id,
+		id:      id,
+		checker: checker,
+		session: clientSession,
 		client: &http.Client{
 			Transport: transport,
 		},
 		config: config,
-		meter:  NewMeter(id),
+		meter:  NewMeter(id, meterConfig),
+		quit:   make(chan struct{}),
 	}, nil
 }
 
@@ -39,17 +73,87 @@ func (Client *Client) GetMeter() *Meter {
 	return Client.meter
 }
 
-func (client *Client) Run(requests chan *Request) {
-	for i := 0; i < client.config.Count; i++ {
-		request := <-requests
+func (client *Client) Stop() {
+	close(client.quit)
+}
+
+func (client *Client) Run(ctx context.Context, requests chan *Request) {
+	for i := 0; client.limit == 0 || i < client.limit; i++ {
+		var request *Request
+		select {
+		case <-client.quit:
+			return
+		default:
+		}
+		select {
+		case <-client.quit:
+			return
+		case request = <-requests:
+		}
 		if request == nil {
-			break
+			return
 		}
-		client.meter.Start()
 		start := time.Now()
-		response, err := client.client.Do(request.Req)
-		res := NewResponse(request, response, err)
-		res.Cost = time.Since(start).Milliseconds()
-		client.meter.Finish(res)
+		client.handle(ctx, request)
+		if !client.pause(ctx, start) {
+			return
+		}
+	}
+}
+
+func (client *Client) handle(ctx context.Context, request *Request) {
+	client.meter.Start()
+	if client.session != nil {
+		client.session.begin(client.client)
+	}
+	if request.prepare != nil {
+		if err := request.prepare(client); err != nil {
+			client.finish(NewResponse(request, nil, err))
+			return
+		}
+	}
+	if request.journey != nil {
+		client.runJourney(ctx, request)
+		return
+	}
+	res := client.send(ctx, request)
+	if !client.deadline.IsZero() && time.Now().After(client.deadline) {
+		return
+	}
+	client.finish(res)
+}
+
+func (client *Client) send(ctx context.Context, request *Request) *Response {
+	if client.inflight != nil {
+		client.inflight.Add(1)
+		defer client.inflight.Add(-1)
+	}
+	reqCtx, cancel := ctx, context.CancelFunc(func() {})
+	if client.config.Timeout > 0 {
+		reqCtx, cancel = context.WithTimeout(ctx, client.config.Timeout)
+	}
+	defer cancel()
+	if client.session != nil {
+		client.session.seed(client.client, request.Req.URL)
+	}
+	start := time.Now()
+	tracer := newTracer(start)
+	req := request.Req.WithContext(httptrace.WithClientTrace(reqCtx, tracer.clientTrace()))
+	response, err := client.client.Do(req)
+	res := NewResponse(request, response, err)
+	end := time.Now()
+	res.Cost = end.Sub(start)
+	if !request.Intended.IsZero() {
+		res.Corrected = end.Sub(request.Intended)
+	}
+	res.Timing = tracer.finish(end)
+	res.CheckFailures = client.checker.Check(res)
+	return res
+}
+
+func (client *Client) finish(res *Response) {
+	client.meter.Finish(res)
+	if client.observe != nil {
+		client.observe(res)
 	}
 }
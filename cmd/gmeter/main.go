@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	gmeter "github.com/venti-org/go-meter"
@@ -15,7 +21,51 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// signalContext is cancelled on the first SIGINT or SIGTERM so the run can
+// stop gracefully, a second signal exits immediately.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		gmeter.ErrPrintln("stopping, waiting for in-flight requests, signal again to exit immediately")
+		cancel()
+		<-signals
+		os.Exit(130)
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+var fromCurlCmd = &cobra.Command{
+	Use:   "from-curl [file]",
+	Short: "convert curl command lines into a jsonl file for --requests-path",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "-"
+		if len(args) != 0 {
+			path = args[0]
+		}
+		specs, err := gmeter.ReadCurlFile(path)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		for _, spec := range specs {
+			if err := encoder.Encode(spec); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
 func init() {
+	rootCmd.AddCommand(fromCurlCmd)
 	methods := []string{"get", "post", "head", "put", "delete", "patch", "connect", "options", "trace"}
 
 	for _, m := range methods {
@@ -24,6 +74,23 @@ func init() {
 		var count *int
 		var url *string
 		var urlsPath *string
+		var requestsPath *string
+		var curlsPath *string
+		var mixPath *string
+		var scenarioPath *string
+		var cookieJar *bool
+		var think *string
+		var search *string
+		var searchStart *float64
+		var searchStep *float64
+		var searchMax *float64
+		var searchP99 *time.Duration
+		var searchErrorRate *float64
+		var searchMinGain *float64
+		var searchRateTolerance *float64
+		var pacing *time.Duration
+		var cookies *[]string
+		var sessionReset *int
 		var body *string
 		var bodyPath *string
 		var bodiesPath *string
@@ -32,34 +99,171 @@ func init() {
 		var proxy *string
 		var headers *[]string
 		var skip *int
+		var rate *float64
+		var rateDrop *bool
+		var duration *time.Duration
+		var stages *[]string
+		var unit *string
+		var failStatus *string
+		var reportJsonPath *string
+		var seriesPath *string
+		var progressInterval *time.Duration
+		var stopTimeout *time.Duration
+		var timeout *time.Duration
+		var connectTimeout *time.Duration
+		var tlsTimeout *time.Duration
+		var headerTimeout *time.Duration
+		var idleTimeout *time.Duration
+		var expectStatus *string
+		var expectBody *string
+		var expectJson *[]string
+		var expectHeaders *[]string
+		var maxLatency *time.Duration
+		var thresholds *[]string
+		var feederPath *string
+		var harPath *string
+		var harHost *string
+		var harUrlPath *string
+		var harMethods *[]string
+		var harPacing *bool
+		var feederMode *string
+		var feederBind *string
+		var seriesInterval *time.Duration
 
 		cmd := &cobra.Command{
 			Use: method,
 			RunE: func(cmd *cobra.Command, args []string) error {
+				var driverStages []gmeter.Stage
+				for _, s := range *stages {
+					if stage, err := gmeter.ParseStage(s); err != nil {
+						return err
+					} else {
+						driverStages = append(driverStages, stage)
+					}
+				}
+				var driverThresholds []*gmeter.Threshold
+				for _, s := range *thresholds {
+					if threshold, err := gmeter.ParseThreshold(s); err != nil {
+						return err
+					} else {
+						driverThresholds = append(driverThresholds, threshold)
+					}
+				}
+				displayUnit, err := gmeter.ParseUnit(*unit)
+				if err != nil {
+					return err
+				}
+				failStatusRule, err := gmeter.ParseStatusRule(*failStatus)
+				if err != nil {
+					return err
+				}
+				var thinkTime *gmeter.ThinkTime
+				if len(*think) != 0 {
+					if thinkTime, err = gmeter.ParseThinkTime(*think); err != nil {
+						return err
+					}
+				}
 				config := &gmeter.DriverConfig{
-					Concurrency: *concurrency,
-					Skip:        *skip,
-					SkipError:   *skipError,
+					Concurrency:      *concurrency,
+					Skip:             *skip,
+					SkipError:        *skipError,
+					Rate:             *rate,
+					RateDrop:         *rateDrop,
+					Duration:         *duration,
+					Stages:           driverStages,
+					ReportJsonPath:   *reportJsonPath,
+					SeriesPath:       *seriesPath,
+					ProgressInterval: *progressInterval,
+					StopTimeout:      *stopTimeout,
+					Thresholds:       driverThresholds,
 					ClientConfig: gmeter.ClientConfig{
-						Count: *count,
-						Proxy: *proxy,
+						Count:          *count,
+						Proxy:          *proxy,
+						Timeout:        *timeout,
+						ConnectTimeout: *connectTimeout,
+						TLSTimeout:     *tlsTimeout,
+						HeaderTimeout:  *headerTimeout,
+						IdleTimeout:    *idleTimeout,
+						Think:          thinkTime,
+						Pacing:         *pacing,
+						Checks: gmeter.CheckConfig{
+							Status:     *expectStatus,
+							BodyRegex:  *expectBody,
+							JsonPaths:  *expectJson,
+							Headers:    *expectHeaders,
+							MaxLatency: *maxLatency,
+						},
+						Session: gmeter.SessionConfig{
+							CookieJar:  *cookieJar,
+							Cookies:    *cookies,
+							ResetEvery: *sessionReset,
+						},
+					},
+					MeterConfig: gmeter.MeterConfig{
+						Unit:           displayUnit,
+						FailStatus:     failStatusRule,
+						SeriesInterval: *seriesInterval,
 					},
 					RequestGeneratorConfig: gmeter.RequestGeneratorConfig{
 						Headers:       *headers,
 						Method:        strings.ToUpper(method),
 						Url:           *url,
 						UrlsPath:      *urlsPath,
+						RequestsPath:  *requestsPath,
+						CurlsPath:     *curlsPath,
+						MixPath:       *mixPath,
+						ScenarioPath:  *scenarioPath,
 						Body:          *body,
 						BodyPath:      *bodyPath,
 						BodiesPath:    *bodiesPath,
 						ExtraJsonPath: *extraJsonPath,
+						Har: gmeter.HarConfig{
+							Path:       *harPath,
+							Host:       *harHost,
+							UrlPath:    *harUrlPath,
+							Methods:    *harMethods,
+							KeepPacing: *harPacing,
+						},
+						Feeder: gmeter.FeederConfig{
+							Path: *feederPath,
+							Mode: *feederMode,
+							Bind: *feederBind,
+						},
 					},
 				}
+				if len(*search) != 0 {
+					searchConfig := &gmeter.SearchConfig{
+						Mode:          *search,
+						Start:         *searchStart,
+						Step:          *searchStep,
+						Max:           *searchMax,
+						MaxP99:        *searchP99,
+						MaxErrorRate:  *searchErrorRate / 100,
+						MinGain:       *searchMinGain / 100,
+						RateTolerance: *searchRateTolerance / 100,
+					}
+					if cmd.Flags().Changed("concurrency") {
+						searchConfig.Clients = *concurrency
+					}
+					cmd.SilenceUsage = true
+					cmd.SilenceErrors = true
+					ctx, cancel := signalContext()
+					defer cancel()
+					steps, best, err := gmeter.Search(ctx, config, searchConfig)
+					if len(steps) != 0 {
+						gmeter.PrintSearch(searchConfig, displayUnit, steps, best)
+					}
+					return err
+				}
 				if driver, err := gmeter.NewDriver(config); err != nil {
 					return err
 				} else {
+					cmd.SilenceUsage = true
+					cmd.SilenceErrors = true
+					ctx, cancel := signalContext()
+					defer cancel()
 					var errs []error
-					errs = append(errs, driver.Run())
+					errs = append(errs, driver.Run(ctx))
 					errs = append(errs, driver.Close())
 					return gmeter.GainError(errs)
 				}
@@ -72,6 +276,23 @@ func init() {
 		skip = cmd.PersistentFlags().IntP("skip", "s", 0, "")
 		url = cmd.PersistentFlags().StringP("url", "u", "", "")
 		urlsPath = cmd.PersistentFlags().String("urls-path", "", "")
+		curlsPath = cmd.PersistentFlags().String("curls-path", "", "file of curl command lines to send, - for stdin")
+		mixPath = cmd.PersistentFlags().String("mix", "", "json list of named endpoints picked by weight")
+		search = cmd.PersistentFlags().String("search", "", "step concurrency or rate up run by run of --duration until latency, errors or throughput hit the knee, rate needs --concurrency")
+		searchStart = cmd.PersistentFlags().Float64("search-start", 0, "first concurrency or rate of --search, defaults to --search-step")
+		searchStep = cmd.PersistentFlags().Float64("search-step", 10, "increase of concurrency or rate per --search step")
+		searchMax = cmd.PersistentFlags().Float64("search-max", 0, "last concurrency or rate of --search, 0 for no limit")
+		searchP99 = cmd.PersistentFlags().Duration("search-p99", 0, "knee when a --search step p99 exceeds this")
+		searchErrorRate = cmd.PersistentFlags().Float64("search-error-rate", 1, "knee when a --search step error rate exceeds this percent")
+		searchMinGain = cmd.PersistentFlags().Float64("search-min-gain", 50, "knee when a --search step gains less than this percent of the qps linear scaling from the best step would give")
+		searchRateTolerance = cmd.PersistentFlags().Float64("search-rate-tolerance", 5, "knee when a --search rate step falls short of its rate by more than this percent")
+		think = cmd.PersistentFlags().String("think", "", "pause of a client after each request: 500ms, 100ms-500ms, normal:500ms,100ms or exp:500ms")
+		pacing = cmd.PersistentFlags().Duration("pacing", 0, "start one request per interval on each client regardless of latency, overrides --think")
+		cookieJar = cmd.PersistentFlags().Bool("cookie-jar", false, "keep an isolated cookie jar and session variables per client")
+		cookies = cmd.PersistentFlags().StringArray("cookie", []string{}, "cookie name=value seeded in every client session, implies --cookie-jar")
+		sessionReset = cmd.PersistentFlags().Int("session-reset", 0, "start a new client session every n requests to simulate new visitors, implies --cookie-jar")
+		scenarioPath = cmd.PersistentFlags().String("scenario", "", "json journey of steps run in order by each client, extracting variables for later steps")
+		requestsPath = cmd.PersistentFlags().String("requests-path", "", "jsonl file, each line {method, url, headers, body or body_base64, label}, url, headers and body may hold templates")
 		proxy = cmd.PersistentFlags().StringP("proxy", "p", "", "")
 		body = cmd.PersistentFlags().StringP("body", "b", "", "")
 		bodyPath = cmd.PersistentFlags().String("body-path", "", "")
@@ -79,12 +300,45 @@ func init() {
 		extraJsonPath = cmd.PersistentFlags().String("extra-json-path", "", "")
 		skipError = cmd.PersistentFlags().Bool("skip-error", false, "")
 		headers = cmd.PersistentFlags().StringArrayP("headers", "H", []string{}, "")
+		rate = cmd.PersistentFlags().Float64("rate", 0, "target requests per second, 0 sends as fast as clients finish")
+		duration = cmd.PersistentFlags().DurationP("duration", "d", 0, "run until the duration elapses instead of sending concurrency * client-count requests")
+		stages = cmd.PersistentFlags().StringArray("stage", []string{}, "load stage duration:target[:linear|step], target is a concurrency (50) or a rate (200rps)")
+		unit = cmd.PersistentFlags().String("unit", "auto", "latency display unit: auto, us, ms or s")
+		failStatus = cmd.PersistentFlags().String("fail-status", "5xx", "status codes counted as failed, e.g. 5xx,429,400-403")
+		reportJsonPath = cmd.PersistentFlags().String("report-json", "", "write the summary report as json to the file, - for stdout")
+		seriesPath = cmd.PersistentFlags().String("series", "", "write the per-interval time series to the file, csv if it ends with .csv else json, - for stdout")
+		seriesInterval = cmd.PersistentFlags().Duration("series-interval", time.Second, "time series bucket width")
+		progressInterval = cmd.PersistentFlags().Duration("progress", time.Second, "refresh interval of the live progress on stderr, 0 disables it")
+		stopTimeout = cmd.PersistentFlags().Duration("stop-timeout", 10*time.Second, "how long to wait for in-flight requests after an interrupt")
+		timeout = cmd.PersistentFlags().DurationP("timeout", "t", 0, "per-request timeout including reading the body, 0 means none")
+		connectTimeout = cmd.PersistentFlags().Duration("connect-timeout", 30*time.Second, "tcp connect timeout")
+		tlsTimeout = cmd.PersistentFlags().Duration("tls-timeout", 10*time.Second, "tls handshake timeout")
+		headerTimeout = cmd.PersistentFlags().Duration("header-timeout", 0, "timeout waiting for response headers, 0 means none")
+		idleTimeout = cmd.PersistentFlags().Duration("idle-timeout", 90*time.Second, "how long idle keep-alive connections are kept")
+		expectStatus = cmd.PersistentFlags().String("expect-status", "", "check the status code, e.g. 200,201 or 2xx")
+		expectBody = cmd.PersistentFlags().String("expect-body", "", "check the body matches the regex")
+		expectJson = cmd.PersistentFlags().StringArray("expect-json", []string{}, "check a json path exists (data.id) or equals a value (data.items[0].id=7)")
+		expectHeaders = cmd.PersistentFlags().StringArray("expect-header", []string{}, "check a header is present (Name) or equal (Name: value)")
+		maxLatency = cmd.PersistentFlags().Duration("max-latency", 0, "check every request finishes within the latency")
+		thresholds = cmd.PersistentFlags().StringArray("threshold", []string{}, "run-level criterion such as p99<250ms, error_rate<0.5% or qps>1000, a miss exits with code 2")
+		feederPath = cmd.PersistentFlags().String("feeder", "", "csv file with a header row whose columns are template variables, e.g. {{.user_id}}")
+		feederMode = cmd.PersistentFlags().String("feeder-mode", gmeter.FeederSequential, "how rows are picked: sequential, ending the run after the last row even with --duration, random or circular")
+		feederBind = cmd.PersistentFlags().String("feeder-bind", gmeter.BindRequest, "bind a row per request or per client")
+		harPath = cmd.PersistentFlags().String("har", "", "replay the requests of a har file")
+		harHost = cmd.PersistentFlags().String("har-host", "", "only replay har entries whose host matches the regex")
+		harUrlPath = cmd.PersistentFlags().String("har-path", "", "only replay har entries whose url path matches the regex")
+		harMethods = cmd.PersistentFlags().StringSlice("har-method", []string{}, "only replay har entries with these methods")
+		harPacing = cmd.PersistentFlags().Bool("har-pacing", false, "keep the original relative timing between har entries")
+		rateDrop = cmd.PersistentFlags().Bool("rate-drop", false, "drop scheduled requests when no client is free instead of delaying them")
 	}
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		gmeter.ErrPrintln(err.Error())
+		if errors.Is(err, gmeter.ErrThresholdsFailed) {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 }
@@ -1,25 +1,58 @@
 package gmeter
 
+import "time"
+
 type DriverConfig struct {
 	Concurrency            int
 	Skip                   int
 	SkipError              bool
+	Rate                   float64
+	RateDrop               bool
+	Duration               time.Duration
+	Stages                 []Stage
+	ReportJsonPath         string
+	SeriesPath             string
+	ProgressInterval       time.Duration
+	StopTimeout            time.Duration
+	Thresholds             []*Threshold
 	ClientConfig           ClientConfig
+	MeterConfig            MeterConfig
 	RequestGeneratorConfig RequestGeneratorConfig
 }
 
+type MeterConfig struct {
+	Unit           Unit
+	FailStatus     *StatusRule
+	SeriesInterval time.Duration
+}
+
 type ClientConfig struct {
-	Count int
-	Proxy string
+	Count          int
+	Proxy          string
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	TLSTimeout     time.Duration
+	HeaderTimeout  time.Duration
+	IdleTimeout    time.Duration
+	Checks         CheckConfig
+	Session        SessionConfig
+	Think          *ThinkTime
+	Pacing         time.Duration
 }
 
 type RequestGeneratorConfig struct {
-	Headers       []string
-	Method        string
-	Url           string
-	UrlsPath      string
-	Body          string
-	BodyPath      string
-	BodiesPath    string
-	ExtraJsonPath string
+	Headers       []string     `json:"headers,omitempty"`
+	Method        string       `json:"method"`
+	Url           string       `json:"url,omitempty"`
+	UrlsPath      string       `json:"urls_path,omitempty"`
+	RequestsPath  string       `json:"requests_path,omitempty"`
+	CurlsPath     string       `json:"curls_path,omitempty"`
+	MixPath       string       `json:"mix_path,omitempty"`
+	ScenarioPath  string       `json:"scenario_path,omitempty"`
+	Body          string       `json:"body,omitempty"`
+	BodyPath      string       `json:"body_path,omitempty"`
+	BodiesPath    string       `json:"bodies_path,omitempty"`
+	ExtraJsonPath string       `json:"extra_json_path,omitempty"`
+	Feeder        FeederConfig `json:"feeder"`
+	Har           HarConfig    `json:"har"`
 }
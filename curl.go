@@ -0,0 +1,324 @@
+package gmeter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// splitCommands splits shell text into commands of words, honouring single
+// and double quotes, backslash escapes and line continuations. Unquoted
+// newlines end a command and lines starting with # are skipped.
+func splitCommands(text string) ([][]string, error) {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) != 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '#' && !inWord && len(words) == 0:
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+			endCommand()
+		case c == '\n':
+			endCommand()
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord()
+		case c == '\\':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				i++
+				continue
+			}
+			if i+2 < len(text) && text[i+1] == '\r' && text[i+2] == '\n' {
+				i += 2
+				continue
+			}
+			if i+1 < len(text) {
+				i++
+				word.WriteByte(text[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(text[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(text[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case c == '"':
+			i++
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("\"\\$`\n", text[i+1]) >= 0 {
+					i++
+					if text[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == '$' && i+1 < len(text) && text[i+1] == '\'':
+			i += 2
+			for ; i < len(text) && text[i] != '\''; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+					switch text[i] {
+					case 'n':
+						word.WriteByte('\n')
+					case 't':
+						word.WriteByte('\t')
+					case 'r':
+						word.WriteByte('\r')
+					default:
+						word.WriteByte(text[i])
+					}
+					continue
+				}
+				word.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("unterminated $' quote")
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands, nil
+}
+
+var curlArgFlags = map[string]string{
+	"-X": "request", "--request": "request",
+	"-H": "header", "--header": "header",
+	"-d": "data", "--data": "data", "--data-ascii": "data",
+	"--data-raw": "data-raw", "--data-binary": "data-binary", "--data-urlencode": "data-urlencode",
+	"-u": "user", "--user": "user",
+	"-A": "user-agent", "--user-agent": "user-agent",
+	"-b": "cookie", "--cookie": "cookie",
+	"-e": "referer", "--referer": "referer",
+	"--url": "url",
+	"-o":    "", "--output": "", "-m": "", "--max-time": "", "--connect-timeout": "",
+	"-w": "", "--write-out": "", "--retry": "", "-x": "", "--proxy": "", "--max-redirs": "",
+}
+
+var curlBoolFlags = map[string]string{
+	"--compressed": "", "-k": "", "--insecure": "", "-s": "", "--silent": "", "-S": "", "--show-error": "",
+	"-L": "", "--location": "", "-v": "", "--verbose": "", "-i": "", "--include": "", "-f": "", "--fail": "",
+	"-N": "", "--no-buffer": "", "--http1.1": "", "--http2": "",
+	"-G": "get", "--get": "get", "-I": "head", "--head": "head",
+}
+
+func curlData(kind string, value string) (string, error) {
+	if kind == "data-urlencode" {
+		return curlUrlencode(value)
+	}
+	if kind == "data-raw" || !strings.HasPrefix(value, "@") {
+		return value, nil
+	}
+	content, err := os.ReadFile(value[1:])
+	if err != nil {
+		return "", err
+	}
+	if kind == "data-binary" {
+		return string(content), nil
+	}
+	return strings.NewReplacer("\r", "", "\n", "").Replace(string(content)), nil
+}
+
+// curlUrlencode encodes a --data-urlencode value of the forms content,
+// =content, name=content, @file or name@file.
+func curlUrlencode(value string) (string, error) {
+	name, content := "", value
+	if i := strings.IndexAny(value, "=@"); i >= 0 {
+		name, content = value[:i], value[i+1:]
+		if value[i] == '@' {
+			b, err := os.ReadFile(content)
+			if err != nil {
+				return "", err
+			}
+			content = string(b)
+		}
+	}
+	if len(name) == 0 {
+		return url.QueryEscape(content), nil
+	}
+	return name + "=" + url.QueryEscape(content), nil
+}
+
+// ParseCurl converts the words of one curl command line into a request spec.
+func ParseCurl(words []string) (*RequestSpec, error) {
+	if len(words) == 0 || words[0] != "curl" {
+		return nil, fmt.Errorf("not a curl command")
+	}
+	spec := &RequestSpec{}
+	var data []string
+	get, head := false, false
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		flag, value, hasValue := word, "", false
+		if strings.HasPrefix(word, "--") {
+			flag, value, hasValue = strings.Cut(word, "=")
+		} else if strings.HasPrefix(word, "-") && len(word) > 2 {
+			if _, ok := curlArgFlags[word[:2]]; ok {
+				flag, value, hasValue = word[:2], word[2:], true
+			} else {
+				for _, c := range word[1:] {
+					if kind, ok := curlBoolFlags["-"+string(c)]; !ok {
+						return nil, fmt.Errorf("unsupported curl flag %v", word)
+					} else {
+						get, head = get || kind == "get", head || kind == "head"
+					}
+				}
+				continue
+			}
+		}
+		if !strings.HasPrefix(word, "-") {
+			spec.Url = word
+			continue
+		}
+		if kind, ok := curlBoolFlags[flag]; ok {
+			get, head = get || kind == "get", head || kind == "head"
+			continue
+		}
+		kind, ok := curlArgFlags[flag]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curl flag %v", flag)
+		}
+		if !hasValue {
+			if i+1 >= len(words) {
+				return nil, fmt.Errorf("curl flag %v needs a value", flag)
+			}
+			i++
+			value = words[i]
+		}
+		switch kind {
+		case "request":
+			spec.Method = strings.ToUpper(value)
+		case "header":
+			spec.Headers = append(spec.Headers, parseHeaders([]string{value})...)
+		case "data", "data-raw", "data-binary", "data-urlencode":
+			if content, err := curlData(kind, value); err != nil {
+				return nil, err
+			} else {
+				data = append(data, content)
+			}
+		case "user":
+			spec.Headers = append(spec.Headers, &Header{
+				Key:   "Authorization",
+				Value: "Basic " + base64.StdEncoding.EncodeToString([]byte(value)),
+			})
+		case "user-agent":
+			spec.Headers = append(spec.Headers, &Header{Key: "User-Agent", Value: value})
+		case "cookie":
+			spec.Headers = append(spec.Headers, &Header{Key: "Cookie", Value: value})
+		case "referer":
+			spec.Headers = append(spec.Headers, &Header{Key: "Referer", Value: value})
+		case "url":
+			spec.Url = value
+		}
+	}
+	if len(spec.Url) == 0 {
+		return nil, fmt.Errorf("curl command without url")
+	}
+	if !strings.Contains(spec.Url, "://") {
+		spec.Url = "http://" + spec.Url
+	}
+	body := strings.Join(data, "&")
+	if get && len(data) != 0 {
+		if strings.Contains(spec.Url, "?") {
+			spec.Url += "&" + body
+		} else {
+			spec.Url += "?" + body
+		}
+	} else if len(data) != 0 {
+		if utf8.ValidString(body) {
+			spec.Body, _ = json.Marshal(body)
+		} else {
+			spec.BodyBase64 = base64.StdEncoding.EncodeToString([]byte(body))
+		}
+		hasContentType := false
+		for _, header := range spec.Headers {
+			hasContentType = hasContentType || strings.EqualFold(header.Key, "Content-Type")
+		}
+		if !hasContentType {
+			spec.Headers = append(spec.Headers, &Header{Key: "Content-Type", Value: "application/x-www-form-urlencoded"})
+		}
+	}
+	if len(spec.Method) == 0 {
+		switch {
+		case head:
+			spec.Method = "HEAD"
+		case len(data) != 0 && !get:
+			spec.Method = "POST"
+		default:
+			spec.Method = "GET"
+		}
+	}
+	return spec, nil
+}
+
+// ParseCurlCommands parses every curl command in text, one per logical line.
+func ParseCurlCommands(text string) ([]*RequestSpec, error) {
+	commands, err := splitCommands(text)
+	if err != nil {
+		return nil, err
+	}
+	var specs []*RequestSpec
+	for i, words := range commands {
+		if spec, err := ParseCurl(words); err != nil {
+			return nil, fmt.Errorf("curl command %v: %v", i+1, err)
+		} else {
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+// ReadCurlFile parses the curl commands in a file, "-" meaning stdin.
+func ReadCurlFile(path string) ([]*RequestSpec, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	specs, err := ParseCurlCommands(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("%v: no curl command", path)
+	}
+	return specs, nil
+}
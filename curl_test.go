@@ -0,0 +1,159 @@
+package gmeter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want [][]string
+		err  bool
+	}{
+		{"words", "curl -s http://a", [][]string{{"curl", "-s", "http://a"}}, false},
+		{"blanks", "  curl\t http://a  \r\n", [][]string{{"curl", "http://a"}}, false},
+		{"single quotes", `curl -H 'X-A: b "c" \d' http://a`, [][]string{{"curl", "-H", `X-A: b "c" \d`, "http://a"}}, false},
+		{"double quotes", `curl -d "a \"b\" \$c \\ \n" http://a`, [][]string{{"curl", "-d", `a "b" $c \ \n`, "http://a"}}, false},
+		{"ansi quotes", `curl -d $'a\nb\tc\'d' http://a`, [][]string{{"curl", "-d", "a\nb\tc'd", "http://a"}}, false},
+		{"adjacent quotes", `curl -d a'b c'"d e"f http://a`, [][]string{{"curl", "-d", "ab cd ef", "http://a"}}, false},
+		{"escaped space", `curl http://a/b\ c`, [][]string{{"curl", "http://a/b c"}}, false},
+		{"empty quotes", `curl -d '' http://a`, [][]string{{"curl", "-d", "", "http://a"}}, false},
+		{"continuation", "curl \\\n  -X POST \\\n  http://a", [][]string{{"curl", "-X", "POST", "http://a"}}, false},
+		{"crlf continuation", "curl \\\r\n  http://a\r\n", [][]string{{"curl", "http://a"}}, false},
+		{"quoted newline", "curl -d 'a\nb' http://a", [][]string{{"curl", "-d", "a\nb", "http://a"}}, false},
+		{"double quoted continuation", "curl -d \"a\\\nb\" http://a", [][]string{{"curl", "-d", "ab", "http://a"}}, false},
+		{"commands", "curl http://a\n\ncurl http://b\n", [][]string{{"curl", "http://a"}, {"curl", "http://b"}}, false},
+		{"comments", "# first\ncurl http://a#frag\n  # second\n", [][]string{{"curl", "http://a#frag"}}, false},
+		{"unterminated single", "curl 'http://a", nil, true},
+		{"unterminated double", `curl "http://a`, nil, true},
+		{"unterminated ansi", `curl $'http://a`, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := splitCommands(test.text)
+			if (err != nil) != test.err {
+				t.Fatalf("error %v, want error %v", err, test.err)
+			}
+			if !test.err && !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseCurl(t *testing.T) {
+	dataFile := writeTestFile(t, "data.txt", "a=1\r\nb=2\n")
+	textFile := writeTestFile(t, "text.txt", "x y&z\n")
+	tests := []struct {
+		name    string
+		command string
+		method  string
+		url     string
+		headers []string
+		body    string
+		err     bool
+	}{
+		{name: "get", command: "curl http://a/b", method: "GET", url: "http://a/b"},
+		{name: "no scheme", command: "curl a/b", method: "GET", url: "http://a/b"},
+		{name: "url flag", command: "curl --url http://a", method: "GET", url: "http://a"},
+		{name: "method", command: "curl -X put http://a", method: "PUT", url: "http://a"},
+		{name: "attached method", command: "curl -XDELETE http://a", method: "DELETE", url: "http://a"},
+		{name: "long flag value", command: "curl --request=PATCH http://a", method: "PATCH", url: "http://a"},
+		{name: "headers", command: `curl -H 'Accept: text/html' --header "X-A:  b" http://a`, method: "GET", url: "http://a",
+			headers: []string{"Accept: text/html", "X-A: b"}},
+		{name: "combined short flags", command: "curl -sSLk --compressed http://a", method: "GET", url: "http://a"},
+		{name: "combined head", command: "curl -sI http://a", method: "HEAD", url: "http://a"},
+		{name: "unknown combined flag", command: "curl -sZ http://a", err: true},
+		{name: "unknown flag", command: "curl --frobnicate http://a", err: true},
+		{name: "missing value", command: "curl http://a -H", err: true},
+		{name: "missing url", command: "curl -s", err: true},
+		{name: "not curl", command: "wget http://a", err: true},
+		{name: "data", command: "curl -d a=1 -d b=2 http://a", method: "POST", url: "http://a",
+			headers: []string{"Content-Type: application/x-www-form-urlencoded"}, body: "a=1&b=2"},
+		{name: "data keeps content type", command: `curl -H 'content-type: application/json' -d '{"a":1}' http://a`, method: "POST", url: "http://a",
+			headers: []string{"content-type: application/json"}, body: `{"a":1}`},
+		{name: "data with method", command: "curl -X PUT -d a http://a", method: "PUT", url: "http://a",
+			headers: []string{"Content-Type: application/x-www-form-urlencoded"}, body: "a"},
+		{name: "data file", command: "curl -d @" + dataFile + " http://a", method: "POST", url: "http://a",
+			headers: []string{"Content-Type: application/x-www-form-urlencoded"}, body: "a=1b=2"},
+		{name: "data ascii file", command: "curl --data-ascii @" + dataFile + " http://a", method: "POST", url: "http://a",
+			headers: []string{"Content-Type: application/x-www-form-urlencoded"}, body: "a=1b=2"},
+		{name: "data binary file", command: "curl --data-binary @" + dataFile + " http://a", method: "POST", url: "http://a",
+			headers: []string{"Content-Type: application/x-www-form-urlencoded"}, body: "a=1\r\nb=2\n"},
+		{name: "data raw at", command: "curl --data-raw @" + dataFile + " http://a", method: "POST", url: "http://a",
+			headers: []string{"Content-Type: application/x-www-form-urlencoded"}, body: "@" + dataFile},
+		{name: "data missing file", command: "curl -d @/nonexistent/gmeter http://a", err: true},
+		{name: "urlencode", command: "curl --data-urlencode 'q=a b&c' --data-urlencode '=x y' --data-urlencode z/w http://a", method: "POST", url: "http://a",
+			headers: []string{"Content-Type: application/x-www-form-urlencoded"}, body: "q=a+b%26c&x+y&z%2Fw"},
+		{name: "urlencode file", command: "curl --data-urlencode @" + textFile + " --data-urlencode t@" + textFile + " http://a", method: "POST", url: "http://a",
+			headers: []string{"Content-Type: application/x-www-form-urlencoded"}, body: "x+y%26z%0A&t=x+y%26z%0A"},
+		{name: "get data", command: "curl -G -d a=1 -d b=2 http://a/s", method: "GET", url: "http://a/s?a=1&b=2"},
+		{name: "get data query", command: "curl --get --data-urlencode 'q=a b' http://a/s?x=1", method: "GET", url: "http://a/s?x=1&q=a+b"},
+		{name: "get data method", command: "curl -G -X POST -d a=1 http://a", method: "POST", url: "http://a?a=1"},
+		{name: "head", command: "curl --head http://a", method: "HEAD", url: "http://a"},
+		{name: "user", command: "curl -u user:pass http://a", method: "GET", url: "http://a",
+			headers: []string{"Authorization: Basic dXNlcjpwYXNz"}},
+		{name: "agent cookie referer", command: "curl -A ua -b 'a=1; b=2' -e http://r http://a", method: "GET", url: "http://a",
+			headers: []string{"User-Agent: ua", "Cookie: a=1; b=2", "Referer: http://r"}},
+		{name: "ignored flags", command: "curl -o out -m 5 --retry 2 -w '%{http_code}' http://a", method: "GET", url: "http://a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commands, err := splitCommands(test.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec, err := ParseCurl(commands[0])
+			if (err != nil) != test.err {
+				t.Fatalf("error %v, want error %v", err, test.err)
+			}
+			if test.err {
+				return
+			}
+			if spec.Method != test.method || spec.Url != test.url {
+				t.Fatalf("got %v %v, want %v %v", spec.Method, spec.Url, test.method, test.url)
+			}
+			var headers []string
+			for _, header := range spec.Headers {
+				headers = append(headers, header.Key+": "+header.Value)
+			}
+			if !reflect.DeepEqual(headers, test.headers) {
+				t.Fatalf("got headers %q, want %q", headers, test.headers)
+			}
+			body, err := spec.body()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != test.body {
+				t.Fatalf("got body %q, want %q", body, test.body)
+			}
+		})
+	}
+}
+
+func TestParseCurlCommands(t *testing.T) {
+	specs, err := ParseCurlCommands("curl http://a \\\n  -H 'X: 1'\n# skipped\ncurl -d a http://b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || specs[0].Url != "http://a" || len(specs[0].Headers) != 1 || specs[1].Method != "POST" {
+		t.Fatalf("unexpected specs %+v", specs)
+	}
+	if _, err := ParseCurlCommands("curl http://a\ncurl -Q http://b\n"); err == nil || !strings.Contains(err.Error(), "curl command 2") {
+		t.Fatalf("got error %v, want the failing command number", err)
+	}
+}
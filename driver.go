@@ -1,35 +1,170 @@
 package gmeter
 
 import (
+	"context"
+	"fmt"
+	"slices"
 	"sync"
+	"time"
 )
 
 type Driver struct {
-	config    *DriverConfig
-	stopped   bool
-	requests  chan *Request
-	meter     *Meter
-	generator Generator[Request]
+	config      *DriverConfig
+	stopped     bool
+	requests    chan *Request
+	meter       *Meter
+	generator   Generator[Request]
+	env         *templateEnv
+	cycles      int
+	start       time.Time
+	deadline    time.Time
+	plan        *stagePlan
+	stageMeters []*Meter
+	mutex       sync.Mutex
+	clients     []*Client
+	active      []*Client
+	wg          sync.WaitGroup
+	progress    *progress
+	ctx         context.Context
+	abort       context.Context
 }
 
 func NewDriver(config *DriverConfig) (*Driver, error) {
-	generator, err := NewRequestGenerator(&config.RequestGeneratorConfig)
+	var plan *stagePlan
+	if len(config.Stages) != 0 {
+		var err error
+		if plan, err = newStagePlan(config.Stages); err != nil {
+			return nil, err
+		}
+	}
+	env := newTemplateEnv()
+	generator, err := newRequestGenerator(&config.RequestGeneratorConfig, env)
 	if err != nil {
+		env.Close()
 		return nil, err
 	}
+	size := 5
+	if config.Rate > 0 || (plan != nil && plan.rate) {
+		size = 0
+	}
 	return &Driver{
 		config:    config,
 		stopped:   false,
-		requests:  make(chan *Request, 5),
-		meter:     NewMeter(0),
+		requests:  make(chan *Request, size),
+		meter:     NewMeter(0, &config.MeterConfig),
 		generator: generator,
+		env:       env,
+		plan:      plan,
 	}, nil
 }
 
+func (driver *Driver) rateMode() bool {
+	return driver.config.Rate > 0 || (driver.plan != nil && driver.plan.rate)
+}
+
+func (driver *Driver) rateAt(t time.Time) float64 {
+	if driver.plan != nil && driver.plan.rate {
+		_, rate := driver.plan.at(t.Sub(driver.start))
+		return rate
+	}
+	return driver.config.Rate
+}
+
+// schedule returns when the request after the one sent at prev is due,
+// following the current rate even while a stage ramps it up or down.
+func (driver *Driver) schedule(prev time.Time) time.Time {
+	const tick = 10 * time.Millisecond
+	t := prev
+	credit := 0.0
+	for !driver.expired(t) {
+		rate := driver.rateAt(t)
+		if rate > 0 {
+			need := time.Duration((1 - credit) / rate * float64(time.Second))
+			if need <= tick {
+				return t.Add(need)
+			}
+		}
+		credit += rate * tick.Seconds()
+		t = t.Add(tick)
+	}
+	return t
+}
+
+func (driver *Driver) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-driver.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (driver *Driver) push(req *Request) bool {
+	select {
+	case <-driver.ctx.Done():
+		return false
+	case driver.requests <- req:
+		return true
+	}
+}
+
+func (driver *Driver) send(req *Request, next time.Time) bool {
+	if next.IsZero() {
+		return driver.push(req)
+	}
+	if !driver.sleep(time.Until(next)) {
+		return false
+	}
+	select {
+	case driver.requests <- req:
+	default:
+		if driver.config.RateDrop {
+			driver.meter.dropped += 1
+		} else {
+			driver.meter.delayed += 1
+			return driver.push(req)
+		}
+	}
+	return true
+}
+
+func (driver *Driver) rewind(lastID int) error {
+	if err := driver.generator.Close(); err != nil {
+		return err
+	}
+	generator, err := newRequestGenerator(&driver.config.RequestGeneratorConfig, driver.env)
+	if err != nil {
+		return err
+	}
+	generator.index = lastID
+	driver.generator = generator
+	driver.cycles += 1
+	return nil
+}
+
+// paced reports whether requests keep the relative timing of a har file.
+func (driver *Driver) paced() bool {
+	har := &driver.config.RequestGeneratorConfig.Har
+	return len(har.Path) != 0 && har.KeepPacing
+}
+
+func (driver *Driver) expired(t time.Time) bool {
+	return !driver.deadline.IsZero() && !t.Before(driver.deadline)
+}
+
 func (driver *Driver) consume() error {
 	defer close(driver.requests)
 	allCount := driver.config.Concurrency * driver.config.ClientConfig.Count
 	n := 0
+	lastID := 0
+	passCount := 0
+	passStart := driver.start
+	var next time.Time
+	if driver.rateMode() {
+		next = driver.start
+	}
 
 	for {
 		req, err := driver.generator.Generate()
@@ -40,57 +175,285 @@ func (driver *Driver) consume() error {
 			return err
 		}
 		if req == nil {
-			break
+			if driver.deadline.IsZero() || passCount == 0 {
+				break
+			}
+			if err := driver.rewind(lastID); err != nil {
+				return err
+			}
+			passCount = 0
+			passStart = time.Now()
+			continue
 		}
-		if req.ID <= driver.config.Skip {
+		lastID = req.ID
+		if driver.cycles == 0 && req.ID <= driver.config.Skip {
 			continue
 		}
-		driver.requests <- req
+		passCount += 1
+		if driver.paced() {
+			req.Intended = passStart.Add(req.At)
+			if !driver.sleep(time.Until(req.Intended)) {
+				break
+			}
+		}
+		if driver.expired(next) || driver.expired(time.Now()) {
+			break
+		}
+		if !next.IsZero() {
+			req.Intended = next
+		}
+		if !driver.send(req, next) {
+			break
+		}
+		if !next.IsZero() {
+			next = driver.schedule(next)
+		}
 		n += 1
-		if n >= allCount {
+		if driver.deadline.IsZero() && n >= allCount {
 			break
 		}
 	}
 	return nil
 }
 
-func (driver *Driver) Run() error {
-	var clients []*Client
-	for i := range driver.config.Concurrency {
-		if client, err := NewClient(i+1, &driver.config.ClientConfig); err != nil {
-			return err
-		} else {
-			clients = append(clients, client)
+// newClient creates and registers a client, the caller holding the mutex.
+func (driver *Driver) newClient() (*Client, error) {
+	client, err := NewClient(len(driver.clients)+1, &driver.config.ClientConfig, &driver.config.MeterConfig)
+	if err != nil {
+		return nil, err
+	}
+	client.deadline = driver.deadline
+	client.meter.origin = driver.start
+	if driver.deadline.IsZero() && !driver.rateMode() {
+		client.limit = driver.config.ClientConfig.Count
+	}
+	if driver.plan != nil || driver.progress != nil {
+		client.observe = driver.observe
+	}
+	if driver.progress != nil {
+		client.inflight = &driver.progress.inflight
+		client.meter.errPrintln = driver.progress.println
+	}
+	driver.clients = append(driver.clients, client)
+	return client, nil
+}
+
+func (driver *Driver) liveClients() []*Client {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+	return slices.Clone(driver.clients)
+}
+
+// spawn starts a client, the caller holding the mutex.
+func (driver *Driver) spawn(client *Client) {
+	driver.active = append(driver.active, client)
+	driver.wg.Add(1)
+	go func() {
+		defer driver.wg.Done()
+		client.Run(driver.abort, driver.requests)
+	}()
+}
+
+// retire stops the newest client, the caller holding the mutex.
+func (driver *Driver) retire() {
+	if len(driver.active) == 0 {
+		return
+	}
+	last := len(driver.active) - 1
+	driver.active[last].Stop()
+	driver.active = driver.active[:last]
+}
+
+// shutdown stops every client from taking new requests and aborts the
+// requests still in flight once the stop timeout passes.
+func (driver *Driver) shutdown(cancelAbort context.CancelFunc) {
+	driver.mutex.Lock()
+	driver.stopped = true
+	for _, client := range driver.active {
+		client.Stop()
+	}
+	driver.active = nil
+	driver.mutex.Unlock()
+	if driver.config.StopTimeout > 0 {
+		time.AfterFunc(driver.config.StopTimeout, cancelAbort)
+	} else {
+		cancelAbort()
+	}
+}
+
+// control follows the concurrency stages, spawning and retiring clients
+// until the last stage ends.
+func (driver *Driver) control() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for now := time.Now(); !driver.expired(now); {
+		if ok, err := driver.reconcile(driver.plan.concurrencyAt(now.Sub(driver.start))); err != nil {
+			ErrPrintln(err.Error())
+			return
+		} else if !ok {
+			return
+		}
+		select {
+		case <-driver.ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+	}
+}
+
+// reconcile spawns or retires clients until target are active, returning
+// false once the driver is stopped.
+func (driver *Driver) reconcile(target int) (bool, error) {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+	if driver.stopped {
+		return false, nil
+	}
+	for len(driver.active) < target {
+		client, err := driver.newClient()
+		if err != nil {
+			return false, err
+		}
+		driver.spawn(client)
+	}
+	for len(driver.active) > target {
+		driver.retire()
+	}
+	return true, nil
+}
+
+func (driver *Driver) observe(res *Response) {
+	if driver.plan != nil {
+		i, _ := driver.plan.at(time.Since(driver.start))
+		driver.stageMeters[i].Record(res)
+	}
+	if driver.progress != nil {
+		driver.progress.record(res)
+	}
+}
+
+// Run sends requests until the configured count, duration or stages are
+// done or ctx is cancelled, then prints the summaries of whatever completed.
+func (driver *Driver) Run(ctx context.Context) error {
+	abort, cancelAbort := context.WithCancel(context.Background())
+	defer cancelAbort()
+	// driver.ctx also ends at the deadline, so consume never blocks past it.
+	driver.ctx = ctx
+	driver.abort = abort
+	driver.start = time.Now()
+	driver.meter.origin = driver.start
+	if driver.plan != nil {
+		driver.deadline = driver.start.Add(driver.plan.duration())
+		for i := range driver.plan.stages {
+			meter := NewMeter(i+1, &driver.config.MeterConfig)
+			meter.label = fmt.Sprintf("stage%v", i+1)
+			meter.origin = driver.start
+			if driver.plan.rate {
+				meter.children = driver.config.Concurrency
+			} else {
+				meter.children = driver.plan.clients(i)
+			}
+			driver.stageMeters = append(driver.stageMeters, meter)
 		}
+	} else if driver.config.Duration > 0 {
+		driver.deadline = driver.start.Add(driver.config.Duration)
+	}
+	if !driver.deadline.IsZero() {
+		var cancelDeadline context.CancelFunc
+		driver.ctx, cancelDeadline = context.WithDeadline(ctx, driver.deadline)
+		defer cancelDeadline()
+	}
+	if driver.config.ProgressInterval > 0 {
+		driver.progress = newProgress(driver, driver.config.ProgressInterval)
+		driver.progress.start()
 	}
 
+	if driver.plan != nil && !driver.plan.rate {
+		driver.wg.Add(1)
+		go func() {
+			defer driver.wg.Done()
+			driver.control()
+		}()
+	} else {
+		driver.mutex.Lock()
+		var clients []*Client
+		for range driver.config.Concurrency {
+			if client, err := driver.newClient(); err != nil {
+				driver.mutex.Unlock()
+				return err
+			} else {
+				clients = append(clients, client)
+			}
+		}
+		for _, client := range clients {
+			driver.spawn(client)
+		}
+		driver.mutex.Unlock()
+	}
+
+	consumed := make(chan struct{})
 	go func() {
+		defer close(consumed)
 		if err := driver.consume(); err != nil {
 			ErrPrintln(err.Error())
 		}
 	}()
 
-	wg := sync.WaitGroup{}
-	for i := range clients {
-		wg.Add(1)
-		go func(client *Client) {
-			defer wg.Done()
-			client.Run(driver.requests)
-		}(clients[i])
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			driver.shutdown(cancelAbort)
+		case <-finished:
+		}
+	}()
+	driver.wg.Wait()
+	<-consumed
+	close(finished)
+	if driver.progress != nil {
+		driver.progress.stop()
 	}
-	wg.Wait()
-	for _, client := range clients {
+	report := &Report{
+		Config: NewConfigReport(driver.config),
+	}
+	for _, client := range driver.clients {
 		meter := client.GetMeter()
+		if !driver.deadline.IsZero() && driver.plan == nil && ctx.Err() == nil {
+			meter.setWindow(driver.start, driver.deadline)
+		}
 		driver.meter.Extend(meter)
 		meter.Summary()
+		report.Clients = append(report.Clients, meter.Report())
+	}
+	stageStart := driver.start
+	for i, meter := range driver.stageMeters {
+		stageEnd := stageStart.Add(driver.plan.stages[i].Duration)
+		meter.setWindow(stageStart, stageEnd)
+		meter.Summary()
+		report.Stages = append(report.Stages, meter.Report())
+		stageStart = stageEnd
 	}
 	driver.meter.Summary()
-	return nil
+	report.Summary = driver.meter.Report()
+	var errs []error
+	errs = append(errs, CheckThresholds(driver.config.Thresholds, report.Summary))
+	if len(driver.config.SeriesPath) != 0 {
+		errs = append(errs, WriteSeries(driver.config.SeriesPath, driver.meter.Series()))
+	}
+	if len(driver.config.ReportJsonPath) != 0 {
+		errs = append(errs, WriteReport(driver.config.ReportJsonPath, report))
+	}
+	if n := driver.meter.CheckFailed(); n != 0 {
+		errs = append(errs, fmt.Errorf("%v requests failed checks", n))
+	}
+	return GainError(errs)
 }
 
 func (driver *Driver) Close() error {
+	var errs []error
 	if driver.generator != nil {
-		return driver.generator.Close()
+		errs = append(errs, driver.generator.Close())
 	}
-	return nil
+	errs = append(errs, driver.env.Close())
+	return GainError(errs)
 }
@@ -0,0 +1,162 @@
+package gmeter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+)
+
+const (
+	FeederSequential = "sequential"
+	FeederRandom     = "random"
+	FeederCircular   = "circular"
+
+	BindRequest = "request"
+	BindClient  = "client"
+)
+
+type FeederConfig struct {
+	Path string `json:"path,omitempty"`
+	Mode string `json:"mode,omitempty"`
+	Bind string `json:"bind,omitempty"`
+}
+
+type csvGenerator struct {
+	file   *FileGenerator
+	reader *csv.Reader
+	header []string
+}
+
+// NewCsvFileGenerator reads a csv file with a header row and generates every
+// following row as a map from column name to value. Quoted fields may span
+// lines and blank lines are skipped.
+func NewCsvFileGenerator(path string) (Generator[map[string]string], error) {
+	file, err := NewFileGenerator(path)
+	if err != nil {
+		return nil, err
+	}
+	generator := &csvGenerator{
+		file:   file,
+		reader: csv.NewReader(file.reader),
+	}
+	generator.reader.FieldsPerRecord = -1
+	if header, err := generator.read(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("csv %v: %v", path, err)
+	} else if header == nil {
+		file.Close()
+		return nil, fmt.Errorf("csv %v: missing header row", path)
+	} else {
+		generator.header = header
+	}
+	return generator, nil
+}
+
+func (generator *csvGenerator) read() ([]string, error) {
+	for {
+		fields, err := generator.reader.Read()
+		if err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(fields) != 1 || len(strings.TrimSpace(fields[0])) != 0 {
+			return fields, nil
+		}
+	}
+}
+
+func (generator *csvGenerator) Generate() (*map[string]string, error) {
+	fields, err := generator.read()
+	if err != nil || fields == nil {
+		return nil, err
+	}
+	row := make(map[string]string, len(generator.header))
+	for i, name := range generator.header {
+		if i < len(fields) {
+			row[name] = fields[i]
+		}
+	}
+	return &row, nil
+}
+
+func (generator *csvGenerator) Close() error {
+	return generator.file.Close()
+}
+
+// Feeder hands out csv rows to requests or clients, safe for concurrent use.
+type Feeder struct {
+	mutex     sync.Mutex
+	mode      string
+	generator Generator[map[string]string]
+	rows      []map[string]string
+	next      int
+}
+
+func NewFeeder(config *FeederConfig) (*Feeder, error) {
+	mode := config.Mode
+	if len(mode) == 0 {
+		mode = FeederSequential
+	}
+	if mode != FeederSequential && mode != FeederRandom && mode != FeederCircular {
+		return nil, fmt.Errorf("unknown feeder mode %q", mode)
+	}
+	if config.Bind != "" && config.Bind != BindRequest && config.Bind != BindClient {
+		return nil, fmt.Errorf("unknown feeder bind %q", config.Bind)
+	}
+	generator, err := NewCsvFileGenerator(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	feeder := &Feeder{
+		mode:      mode,
+		generator: generator,
+	}
+	if mode == FeederSequential {
+		return feeder, nil
+	}
+	defer generator.Close()
+	feeder.generator = nil
+	for {
+		if row, err := generator.Generate(); err != nil {
+			return nil, err
+		} else if row == nil {
+			break
+		} else {
+			feeder.rows = append(feeder.rows, *row)
+		}
+	}
+	if len(feeder.rows) == 0 {
+		return nil, fmt.Errorf("csv %v: no rows", config.Path)
+	}
+	return feeder, nil
+}
+
+// Next returns the next row, or nil once a sequential feeder is exhausted.
+func (feeder *Feeder) Next() (map[string]string, error) {
+	feeder.mutex.Lock()
+	defer feeder.mutex.Unlock()
+	switch feeder.mode {
+	case FeederRandom:
+		return feeder.rows[rand.IntN(len(feeder.rows))], nil
+	case FeederCircular:
+		row := feeder.rows[feeder.next]
+		feeder.next = (feeder.next + 1) % len(feeder.rows)
+		return row, nil
+	}
+	if row, err := feeder.generator.Generate(); err != nil || row == nil {
+		return nil, err
+	} else {
+		return *row, nil
+	}
+}
+
+func (feeder *Feeder) Close() error {
+	if feeder.generator != nil {
+		return feeder.generator.Close()
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package gmeter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCsvFileGenerator(t *testing.T) {
+	path := writeTestFile(t, "rows.csv", "user,note\r\nu1,plain\n\n\"u2\",\"two\nlines, quoted \"\"x\"\"\"\nu3\n")
+	generator, err := NewCsvFileGenerator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer generator.Close()
+	want := []map[string]string{
+		{"user": "u1", "note": "plain"},
+		{"user": "u2", "note": "two\nlines, quoted \"x\""},
+		{"user": "u3"},
+	}
+	for _, row := range want {
+		got, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || !reflect.DeepEqual(*got, row) {
+			t.Fatalf("got row %v, want %v", got, row)
+		}
+	}
+	if got, err := generator.Generate(); got != nil || err != nil {
+		t.Fatalf("got row %v error %v after the last row", got, err)
+	}
+}
+
+func TestSequentialFeederAcrossGenerators(t *testing.T) {
+	config := &RequestGeneratorConfig{
+		Method: "GET",
+		Url:    "http://a/?u={{.user}}",
+		Feeder: FeederConfig{Path: writeTestFile(t, "users.csv", "user\nu1\nu2\nu3\n")},
+	}
+	env := newTemplateEnv()
+	defer env.Close()
+	var urls []string
+	for cycle := 0; cycle < 2; cycle++ {
+		generator, err := newRequestGenerator(config, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			request, err := generator.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if request != nil {
+				urls = append(urls, request.Req.URL.String())
+			}
+		}
+		generator.Close()
+	}
+	want := []string{"http://a/?u=u1", "http://a/?u=u2", "http://a/?u=u3"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Fatalf("got %v, want %v", urls, want)
+	}
+}
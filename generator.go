@@ -35,6 +35,17 @@ func (generator *SimpleGenerator[T]) Close() error {
 	return nil
 }
 
+func NewSliceGenerator[T any](items []*T) *SimpleGenerator[T] {
+	i := 0
+	return NewSimpleGenerator(func() (*T, error) {
+		if i >= len(items) {
+			return nil, nil
+		}
+		i += 1
+		return items[i-1], nil
+	})
+}
+
 type filterGenerator[T any] struct {
 	filterFunc    func(*T) bool
 	prevGenerator Generator[T]
@@ -146,6 +157,16 @@ type RequestGenerator struct {
 	bodyGenerator BodyGenerator
 	index         int
 	headers       []*Header
+	urlTemplate   *Template
+	bodyTemplate  *Template
+	clientScoped  bool
+	feeder        *Feeder
+	specGenerator Generator[RequestSpec]
+	mix           []*mixEntry
+	closed        []*mixEntry
+	journey       *Journey
+	env           *templateEnv
+	ownsEnv       bool
 }
 
 func NewJsonFileGenerator(path string) (Generator[map[string]any], error) {
@@ -175,8 +196,16 @@ func readFileJson(path string) (map[string]any, error) {
 }
 
 type Header struct {
-	Key   string
-	Value string
+	Key      string
+	Value    string
+	template *Template
+}
+
+func (header *Header) render(ctx *TemplateContext) string {
+	if header.template != nil {
+		return header.template.Render(ctx)
+	}
+	return header.Value
 }
 
 func parseHeaders(headers []string) []*Header {
@@ -198,7 +227,21 @@ func parseHeaders(headers []string) []*Header {
 }
 
 func NewRequestGenerator(config *RequestGeneratorConfig) (*RequestGenerator, error) {
+	env := newTemplateEnv()
+	generator, err := newRequestGenerator(config, env)
+	if err != nil {
+		env.Close()
+		return nil, err
+	}
+	generator.ownsEnv = true
+	return generator, nil
+}
+
+// newRequestGenerator compiles templates in env, which keeps template state
+// such as seq counters and the feeder across generators of the same run.
+func newRequestGenerator(config *RequestGeneratorConfig, env *templateEnv) (*RequestGenerator, error) {
 	generator := &RequestGenerator{
+		env:     env,
 		config:  config,
 		method:  config.Method,
 		headers: parseHeaders(config.Headers),
@@ -207,7 +250,50 @@ func NewRequestGenerator(config *RequestGeneratorConfig) (*RequestGenerator, err
 		var bodyReader io.Reader = strings.NewReader(config.Body)
 		return &bodyReader, nil
 	})
-	if len(config.Url) != 0 {
+	templateBody := config.Body
+	if len(config.MixPath) != 0 {
+		mix, err := newMix(config, env)
+		if err != nil {
+			return nil, err
+		}
+		generator.mix = mix
+		return generator, nil
+	}
+	if len(config.ScenarioPath) != 0 {
+		scenario, err := ReadScenario(config.ScenarioPath)
+		if err != nil {
+			return nil, err
+		}
+		if generator.journey, err = newJourney(scenario, config, env); err != nil {
+			return nil, err
+		}
+		if err := generator.setupFeeder(); err != nil {
+			return nil, err
+		}
+		return generator, nil
+	}
+	if len(config.RequestsPath) != 0 {
+		specGenerator, err := NewRequestSpecGenerator(config.RequestsPath)
+		if err != nil {
+			return nil, err
+		}
+		generator.specGenerator = specGenerator
+		templateBody = ""
+	} else if len(config.CurlsPath) != 0 {
+		specs, err := ReadCurlFile(config.CurlsPath)
+		if err != nil {
+			return nil, err
+		}
+		generator.specGenerator = NewSliceGenerator(specs)
+		templateBody = ""
+	} else if len(config.Har.Path) != 0 {
+		specs, err := ReadHar(&config.Har)
+		if err != nil {
+			return nil, err
+		}
+		generator.specGenerator = NewSliceGenerator(specs)
+		templateBody = ""
+	} else if len(config.Url) != 0 {
 		url := &config.Url
 		generator.urlGenerator = NewSimpleGenerator(func() (*string, error) {
 			return url, nil
@@ -222,11 +308,13 @@ func NewRequestGenerator(config *RequestGeneratorConfig) (*RequestGenerator, err
 			} else if body, err = os.ReadFile(config.BodyPath); err != nil {
 				return nil, err
 			}
+			templateBody = string(body)
 			generator.bodyGenerator = NewSimpleGenerator(func() (*io.Reader, error) {
 				var reader io.Reader = bytes.NewReader(body)
 				return &reader, nil
 			})
 		} else if len(config.BodiesPath) != 0 {
+			templateBody = ""
 			if len(config.ExtraJsonPath) != 0 {
 				bodyGenerator, err := NewJsonFileGenerator(config.BodiesPath)
 				if err != nil {
@@ -266,11 +354,97 @@ func NewRequestGenerator(config *RequestGeneratorConfig) (*RequestGenerator, err
 			generator.urlGenerator = urlGenerator
 		}
 	} else {
-		return nil, fmt.Errorf("must set url or urls-path")
+		return nil, fmt.Errorf("must set url, urls-path, requests-path, curls-path, har, mix or scenario")
+	}
+	if err := generator.compileTemplates(env, templateBody); err != nil {
+		generator.Close()
+		return nil, err
+	}
+	if err := generator.setupFeeder(); err != nil {
+		generator.Close()
+		return nil, err
 	}
 	return generator, nil
 }
 
+func (generator *RequestGenerator) setupFeeder() error {
+	if len(generator.config.Feeder.Path) == 0 {
+		return nil
+	}
+	feeder, err := generator.env.openFeeder(&generator.config.Feeder)
+	if err != nil {
+		return err
+	}
+	generator.feeder = feeder
+	if generator.config.Feeder.Bind == BindClient {
+		generator.clientScoped = true
+	}
+	return nil
+}
+
+// nextRow returns the feeder row of the next request, nil when there is no
+// feeder or it is bound to clients, and false once the feeder runs out.
+func (generator *RequestGenerator) nextRow() (map[string]string, bool, error) {
+	if generator.feeder == nil || generator.config.Feeder.Bind == BindClient {
+		return nil, true, nil
+	}
+	row, err := generator.feeder.Next()
+	return row, row != nil, err
+}
+
+// bindClientRow gives client its feeder row the first time it needs one.
+func (generator *RequestGenerator) bindClientRow(client *Client) error {
+	if generator.feeder == nil || generator.config.Feeder.Bind != BindClient || client.row != nil {
+		return nil
+	}
+	row, err := generator.feeder.Next()
+	if err != nil {
+		return err
+	} else if row == nil {
+		return fmt.Errorf("feeder has no row left for client %v", client.id)
+	}
+	client.row = row
+	return nil
+}
+
+// compileTemplates compiles the url, the headers and the static body when they
+// contain template actions.
+func (generator *RequestGenerator) compileTemplates(env *templateEnv, body string) error {
+	var templates []*Template
+	if isTemplate(generator.config.Url) {
+		template, err := env.compile(generator.config.Url)
+		if err != nil {
+			return err
+		}
+		generator.urlTemplate = template
+		templates = append(templates, template)
+	}
+	if isTemplate(body) {
+		template, err := env.compile(body)
+		if err != nil {
+			return err
+		}
+		generator.bodyTemplate = template
+		templates = append(templates, template)
+	}
+	for _, header := range generator.headers {
+		if isTemplate(header.Value) {
+			template, err := env.compile(header.Value)
+			if err != nil {
+				return err
+			}
+			header.template = template
+		}
+		if header.template != nil {
+			templates = append(templates, header.template)
+		}
+	}
+	for _, template := range templates {
+		generator.clientScoped = generator.clientScoped || template.clientScoped
+	}
+	return nil
+}
+
 func (generator *RequestGenerator) nextUrl() (*string, error) {
 	return generator.urlGenerator.Generate()
 }
@@ -279,39 +453,138 @@ func (generator *RequestGenerator) nextBody() (*io.Reader, error) {
 	return generator.bodyGenerator.Generate()
 }
 
+func (generator *RequestGenerator) build(ctx *TemplateContext, url string, body io.Reader) (*http.Request, error) {
+	if generator.urlTemplate != nil {
+		url = generator.urlTemplate.Render(ctx)
+	}
+	if generator.bodyTemplate != nil {
+		body = strings.NewReader(generator.bodyTemplate.Render(ctx))
+	}
+	request, err := http.NewRequest(generator.method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range generator.headers {
+		request.Header.Add(header.Key, header.render(ctx))
+	}
+	if request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return request, nil
+}
+
+func (generator *RequestGenerator) generateSpec() (*Request, error) {
+	spec, err := generator.specGenerator.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("request (id %v) : %v", generator.index, err)
+	} else if spec == nil {
+		return nil, nil
+	}
+	row, ok, err := generator.nextRow()
+	if err != nil {
+		return nil, fmt.Errorf("request (id %v) : %v", generator.index, err)
+	} else if !ok {
+		return nil, nil
+	}
+	request := &Request{
+		ID:    generator.index,
+		Label: spec.Label,
+	}
+	if generator.config.Har.KeepPacing {
+		request.At = spec.At
+	}
+	clientScoped, err := spec.compile(generator.env)
+	if err != nil {
+		return nil, fmt.Errorf("request (id %v) : %v", generator.index, err)
+	}
+	if generator.clientScoped || clientScoped {
+		request.prepare = func(client *Client) error {
+			if err := generator.bindClientRow(client); err != nil {
+				return err
+			}
+			ctx := &TemplateContext{RequestID: request.ID, ClientID: client.id, Vars: []map[string]string{row, client.row}}
+			req, err := spec.build(generator.method, generator.headers, ctx)
+			request.Req = req
+			return err
+		}
+		return request, nil
+	}
+	ctx := &TemplateContext{RequestID: request.ID, Vars: []map[string]string{row}}
+	if request.Req, err = spec.build(generator.method, generator.headers, ctx); err != nil {
+		return nil, fmt.Errorf("request (id %v) : %v", generator.index, err)
+	}
+	return request, nil
+}
+
 func (generator *RequestGenerator) Generate() (*Request, error) {
-	var request *http.Request
 	generator.index += 1
+	if generator.journey != nil {
+		row, ok, err := generator.nextRow()
+		if err != nil {
+			return nil, fmt.Errorf("request (id %v) : %v", generator.index, err)
+		} else if !ok {
+			return nil, nil
+		}
+		return &Request{ID: generator.index, journey: generator.journey, row: row, prepare: generator.bindClientRow}, nil
+	}
+	if generator.mix != nil || generator.closed != nil {
+		return generator.generateMix()
+	}
+	if generator.specGenerator != nil {
+		return generator.generateSpec()
+	}
 	var url *string
 	var body *io.Reader
 	var err error
 	if url, err = generator.nextUrl(); err != nil {
 	} else if body, err = generator.nextBody(); err != nil {
-	} else {
-		if url != nil && body != nil {
-			request, err = http.NewRequest(generator.method, *url, *body)
-		}
 	}
 	if err != nil {
 		return nil, fmt.Errorf("request (id %v) : %v", generator.index, err)
 	}
-	if request == nil {
+	if url == nil || body == nil {
 		return nil, nil
 	}
-	for _, header := range generator.headers {
-		request.Header.Add(header.Key, header.Value)
+	request := &Request{
+		ID: generator.index,
 	}
-	if body != nil && request.Header.Get("Content-Type") == "" {
-		request.Header.Set("Content-Type", "application/json")
+	row, ok, err := generator.nextRow()
+	if err != nil {
+		return nil, fmt.Errorf("request (id %v) : %v", generator.index, err)
+	} else if !ok {
+		return nil, nil
 	}
-	return &Request{
-		ID:  generator.index,
-		Req: request,
-	}, nil
+	if generator.clientScoped {
+		request.prepare = func(client *Client) error {
+			if err := generator.bindClientRow(client); err != nil {
+				return err
+			}
+			ctx := &TemplateContext{RequestID: request.ID, ClientID: client.id, Vars: []map[string]string{row, client.row}}
+			req, err := generator.build(ctx, *url, *body)
+			request.Req = req
+			return err
+		}
+		return request, nil
+	}
+	ctx := &TemplateContext{RequestID: request.ID, Vars: []map[string]string{row}}
+	if request.Req, err = generator.build(ctx, *url, *body); err != nil {
+		return nil, fmt.Errorf("request (id %v) : %v", generator.index, err)
+	}
+	return request, nil
 }
 
 func (generator *RequestGenerator) Close() error {
 	var errs []error
+	for _, entry := range append(generator.mix, generator.closed...) {
+		if err := entry.generator.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if generator.specGenerator != nil {
+		if err := generator.specGenerator.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if generator.urlGenerator != nil {
 		if err := generator.urlGenerator.Close(); err != nil {
 			errs = append(errs, err)
@@ -323,5 +596,10 @@ func (generator *RequestGenerator) Close() error {
 			errs = append(errs, err)
 		}
 	}
+	if generator.ownsEnv {
+		if err := generator.env.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	return GainError(errs)
 }
@@ -0,0 +1,128 @@
+package gmeter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+type HarConfig struct {
+	Path       string   `json:"path,omitempty"`
+	Host       string   `json:"host,omitempty"`
+	UrlPath    string   `json:"url_path,omitempty"`
+	Methods    []string `json:"methods,omitempty"`
+	KeepPacing bool     `json:"keep_pacing,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime time.Time `json:"startedDateTime"`
+			Request         struct {
+				Method   string         `json:"method"`
+				Url      string         `json:"url"`
+				Headers  []harNameValue `json:"headers"`
+				Cookies  []harNameValue `json:"cookies"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+var harSkipHeaders = []string{"Host", "Content-Length", "Connection", "Accept-Encoding"}
+
+// ReadHar turns the entries of a har file that pass the host, path and method
+// filters into request specs, with At set to their offset from the first entry.
+func ReadHar(config *HarConfig) ([]*RequestSpec, error) {
+	content, err := os.ReadFile(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	har := &harFile{}
+	if err := json.Unmarshal(content, har); err != nil {
+		return nil, fmt.Errorf("har %v: %v", config.Path, err)
+	}
+	var hostRe, pathRe *regexp.Regexp
+	if len(config.Host) != 0 {
+		if hostRe, err = regexp.Compile(config.Host); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.UrlPath) != 0 {
+		if pathRe, err = regexp.Compile(config.UrlPath); err != nil {
+			return nil, err
+		}
+	}
+	var methods []string
+	for _, method := range config.Methods {
+		methods = append(methods, strings.ToUpper(method))
+	}
+
+	var specs []*RequestSpec
+	var first time.Time
+	for _, entry := range har.Log.Entries {
+		request := &entry.Request
+		u, err := url.Parse(request.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if (hostRe != nil && !hostRe.MatchString(u.Host)) || (pathRe != nil && !pathRe.MatchString(u.Path)) ||
+			(len(methods) != 0 && !slices.Contains(methods, strings.ToUpper(request.Method))) {
+			continue
+		}
+		if first.IsZero() {
+			first = entry.StartedDateTime
+		}
+		spec := &RequestSpec{
+			Method: request.Method,
+			Url:    request.Url,
+			Label:  request.Method + " " + u.Path,
+			At:     entry.StartedDateTime.Sub(first),
+		}
+		hasCookie := false
+		for _, header := range request.Headers {
+			key := http.CanonicalHeaderKey(header.Name)
+			if strings.HasPrefix(header.Name, ":") || slices.Contains(harSkipHeaders, key) {
+				continue
+			}
+			hasCookie = hasCookie || key == "Cookie"
+			spec.Headers = append(spec.Headers, &Header{Key: key, Value: header.Value})
+		}
+		if !hasCookie && len(request.Cookies) != 0 {
+			var cookies []string
+			for _, cookie := range request.Cookies {
+				cookies = append(cookies, cookie.Name+"="+cookie.Value)
+			}
+			spec.Headers = append(spec.Headers, &Header{Key: "Cookie", Value: strings.Join(cookies, "; ")})
+		}
+		if request.PostData != nil {
+			if body, err := json.Marshal(request.PostData.Text); err == nil {
+				spec.Body = body
+			}
+			if len(request.PostData.MimeType) != 0 && !slices.ContainsFunc(spec.Headers, func(header *Header) bool {
+				return header.Key == "Content-Type"
+			}) {
+				spec.Headers = append(spec.Headers, &Header{Key: "Content-Type", Value: request.PostData.MimeType})
+			}
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("har %v: no entries left after filtering", config.Path)
+	}
+	return specs, nil
+}
@@ -0,0 +1,125 @@
+package gmeter
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	histogramSubBits  = 7
+	histogramSubCount = 1 << histogramSubBits
+	histogramHalf     = histogramSubCount / 2
+)
+
+// Histogram is a log-linear histogram: values below 128 are counted exactly
+// and larger values land in buckets with a relative width of at most 1/64.
+// Histograms with the same layout merge by adding bucket counts.
+type Histogram struct {
+	counts []int64
+	count  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+func histogramIndex(value int64) int {
+	if value < histogramSubCount {
+		return int(value)
+	}
+	shift := bits.Len64(uint64(value)) - histogramSubBits
+	return shift*histogramHalf + int(value>>shift)
+}
+
+func histogramUpper(index int) int64 {
+	if index < histogramSubCount {
+		return int64(index)
+	}
+	shift := index/histogramHalf - 1
+	sub := int64(index%histogramHalf + histogramHalf)
+	return (sub+1)<<shift - 1
+}
+
+func (histogram *Histogram) Record(value int64) {
+	if value < 0 {
+		value = 0
+	}
+	index := histogramIndex(value)
+	if index >= len(histogram.counts) {
+		counts := make([]int64, index+1)
+		copy(counts, histogram.counts)
+		histogram.counts = counts
+	}
+	histogram.counts[index] += 1
+	if histogram.count == 0 || value < histogram.min {
+		histogram.min = value
+	}
+	if value > histogram.max {
+		histogram.max = value
+	}
+	histogram.count += 1
+	histogram.sum += value
+}
+
+func (histogram *Histogram) Merge(other *Histogram) {
+	if other == nil || other.count == 0 {
+		return
+	}
+	if len(other.counts) > len(histogram.counts) {
+		counts := make([]int64, len(other.counts))
+		copy(counts, histogram.counts)
+		histogram.counts = counts
+	}
+	for i, n := range other.counts {
+		histogram.counts[i] += n
+	}
+	if histogram.count == 0 || other.min < histogram.min {
+		histogram.min = other.min
+	}
+	if other.max > histogram.max {
+		histogram.max = other.max
+	}
+	histogram.count += other.count
+	histogram.sum += other.sum
+}
+
+func (histogram *Histogram) Count() int64 {
+	return histogram.count
+}
+
+func (histogram *Histogram) Sum() int64 {
+	return histogram.sum
+}
+
+func (histogram *Histogram) Min() int64 {
+	return histogram.min
+}
+
+func (histogram *Histogram) Max() int64 {
+	return histogram.max
+}
+
+func (histogram *Histogram) Mean() float64 {
+	return div(histogram.sum, histogram.count)
+}
+
+// Percentile returns the highest value equivalent to the given percentile,
+// p ranging from 0 to 100.
+func (histogram *Histogram) Percentile(p float64) int64 {
+	if histogram.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(histogram.count)))
+	rank = min(max(rank, 1), histogram.count)
+	seen := int64(0)
+	for i, n := range histogram.counts {
+		seen += n
+		if seen >= rank {
+			return min(max(histogramUpper(i), histogram.min), histogram.max)
+		}
+	}
+	return histogram.max
+}
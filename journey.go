@@ -0,0 +1,261 @@
+package gmeter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ExtractConfig saves one value of a step response into the variable Var,
+// taken from exactly one of a json path, a regex (first group if any), a
+// header or a cookie.
+type ExtractConfig struct {
+	Var    string `json:"var"`
+	Json   string `json:"json"`
+	Regex  string `json:"regex"`
+	Header string `json:"header"`
+	Cookie string `json:"cookie"`
+}
+
+type StepConfig struct {
+	Name    string           `json:"name"`
+	Method  string           `json:"method"`
+	Url     string           `json:"url"`
+	Headers []string         `json:"headers"`
+	Body    string           `json:"body"`
+	Extract []*ExtractConfig `json:"extract"`
+}
+
+type ScenarioConfig struct {
+	Name  string        `json:"name"`
+	Steps []*StepConfig `json:"steps"`
+}
+
+func ReadScenario(path string) (*ScenarioConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scenario := &ScenarioConfig{}
+	if err := json.Unmarshal(content, scenario); err != nil {
+		return nil, fmt.Errorf("scenario %v: %v", path, err)
+	}
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("scenario %v: no steps", path)
+	}
+	if len(scenario.Name) == 0 {
+		scenario.Name = "journey"
+	}
+	return scenario, nil
+}
+
+type extractor struct {
+	name    string
+	extract func(res *Response) (string, bool)
+}
+
+func newExtractor(config *ExtractConfig) (*extractor, error) {
+	if len(config.Var) == 0 {
+		return nil, fmt.Errorf("extract without var")
+	}
+	e := &extractor{name: config.Var}
+	switch {
+	case len(config.Json) != 0:
+		e.extract = func(res *Response) (string, bool) {
+			value, ok := lookupJsonPath(res.Body, config.Json)
+			if !ok {
+				return "", false
+			}
+			return jsonString(value), true
+		}
+	case len(config.Regex) != 0:
+		re, err := regexp.Compile(config.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("extract %v: %v", config.Var, err)
+		}
+		e.extract = func(res *Response) (string, bool) {
+			match := re.FindStringSubmatch(res.BodyString())
+			if match == nil {
+				return "", false
+			}
+			return match[len(match)-1], true
+		}
+	case len(config.Header) != 0:
+		e.extract = func(res *Response) (string, bool) {
+			values := res.Header.Values(config.Header)
+			if len(values) == 0 {
+				return "", false
+			}
+			return values[0], true
+		}
+	case len(config.Cookie) != 0:
+		e.extract = func(res *Response) (string, bool) {
+			for _, cookie := range (&http.Response{Header: res.Header}).Cookies() {
+				if cookie.Name == config.Cookie {
+					return cookie.Value, true
+				}
+			}
+			return "", false
+		}
+	default:
+		return nil, fmt.Errorf("extract %v: want one of json, regex, header or cookie", config.Var)
+	}
+	return e, nil
+}
+
+type journeyStep struct {
+	name       string
+	method     string
+	url        *Template
+	headers    []*Header
+	body       *Template
+	extractors []*extractor
+}
+
+func (step *journeyStep) build(ctx *TemplateContext) (*http.Request, error) {
+	var body *strings.Reader
+	if step.body != nil {
+		body = strings.NewReader(step.body.Render(ctx))
+	} else {
+		body = strings.NewReader("")
+	}
+	request, err := http.NewRequest(step.method, step.url.Render(ctx), body)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range step.headers {
+		request.Header.Add(header.Key, header.template.Render(ctx))
+	}
+	if request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return request, nil
+}
+
+func (step *journeyStep) extract(res *Response, vars map[string]string) error {
+	for _, e := range step.extractors {
+		value, ok := e.extract(res)
+		if !ok {
+			return fmt.Errorf("extract %v: not found", e.name)
+		}
+		vars[e.name] = value
+	}
+	return nil
+}
+
+// Journey is an ordered list of steps a client runs as one unit, later steps
+// reading the variables extracted from earlier responses as {{.var}}.
+type Journey struct {
+	name  string
+	steps []*journeyStep
+}
+
+func newJourney(scenario *ScenarioConfig, base *RequestGeneratorConfig, env *templateEnv) (*Journey, error) {
+	journey := &Journey{name: scenario.Name}
+	for i, config := range scenario.Steps {
+		step := &journeyStep{
+			name:    config.Name,
+			method:  base.Method,
+			headers: parseHeaders(append(append([]string{}, base.Headers...), config.Headers...)),
+		}
+		if len(step.name) == 0 {
+			step.name = fmt.Sprintf("step%v", i+1)
+		}
+		if len(config.Method) != 0 {
+			step.method = strings.ToUpper(config.Method)
+		}
+		if len(config.Url) == 0 {
+			return nil, fmt.Errorf("step %v: no url", step.name)
+		}
+		var err error
+		if step.url, err = env.compile(config.Url); err != nil {
+			return nil, fmt.Errorf("step %v: %v", step.name, err)
+		}
+		if len(config.Body) != 0 {
+			if step.body, err = env.compile(config.Body); err != nil {
+				return nil, fmt.Errorf("step %v: %v", step.name, err)
+			}
+		}
+		for _, header := range step.headers {
+			if header.template, err = env.compile(header.Value); err != nil {
+				return nil, fmt.Errorf("step %v: %v", step.name, err)
+			}
+		}
+		for _, extract := range config.Extract {
+			e, err := newExtractor(extract)
+			if err != nil {
+				return nil, fmt.Errorf("step %v: %v", step.name, err)
+			}
+			step.extractors = append(step.extractors, e)
+		}
+		journey.steps = append(journey.steps, step)
+	}
+	return journey, nil
+}
+
+// runJourney runs the steps of request in order until one fails, recording
+// the whole journey under its name and every step as journey/step.
+// Extracted variables live for the journey, or for the session if any.
+func (client *Client) runJourney(ctx context.Context, request *Request) {
+	journey := request.journey
+	vars := make(map[string]string)
+	if client.session != nil {
+		vars = client.session.vars
+	}
+	start := time.Now()
+	var steps []*Response
+	for _, step := range journey.steps {
+		stepRequest := &Request{ID: request.ID, Label: journey.name + "/" + step.name}
+		tctx := &TemplateContext{RequestID: request.ID, ClientID: client.id, Vars: []map[string]string{vars, request.row, client.row}}
+		var res *Response
+		if req, err := step.build(tctx); err != nil {
+			res = NewResponse(stepRequest, nil, err)
+		} else {
+			stepRequest.Req = req
+			res = client.send(ctx, stepRequest)
+		}
+		if res.Error == nil && !client.meter.isFailed(res) {
+			if err := step.extract(res, vars); err != nil {
+				res.Error = err
+				res.ErrorClass = ErrorExtract
+			}
+		}
+		steps = append(steps, res)
+		if res.Error != nil || client.meter.isFailed(res) || len(res.CheckFailures) != 0 {
+			break
+		}
+	}
+	if !client.deadline.IsZero() && time.Now().After(client.deadline) {
+		return
+	}
+	for _, res := range steps {
+		client.meter.FinishStep(res)
+	}
+	last := steps[len(steps)-1]
+	res := &Response{
+		ID:         request.ID,
+		Label:      journey.name,
+		RequestUrl: last.RequestUrl,
+		StatusCode: last.StatusCode,
+		ErrorClass: last.ErrorClass,
+		Cost:       time.Since(start),
+	}
+	if !request.Intended.IsZero() {
+		res.Corrected = time.Since(request.Intended)
+	}
+	if last.Error != nil {
+		res.Error = fmt.Errorf("step %v: %v", last.Label, last.Error)
+	}
+	for _, name := range last.CheckFailures {
+		res.CheckFailures = append(res.CheckFailures, fmt.Sprintf("%v: %v", last.Label, name))
+	}
+	client.meter.Record(res)
+	if client.observe != nil {
+		client.observe(res)
+	}
+}
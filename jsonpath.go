@@ -0,0 +1,56 @@
+package gmeter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+func splitJsonPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	if len(path) == 0 {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// lookupJsonPath walks a decoded json value along a path such as
+// "$.items[0].id" or "items.0.id".
+func lookupJsonPath(value any, path string) (any, bool) {
+	if s, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, false
+		}
+	}
+	for _, key := range splitJsonPath(path) {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// jsonString renders a looked up value for comparisons and templates, strings
+// as they are and everything else as json.
+func jsonString(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
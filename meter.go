@@ -2,24 +2,59 @@ package gmeter
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
 type Meter struct {
-	id           int
-	children     int
-	start        time.Time
-	finish       time.Time
-	lastStart    time.Time
-	successItems []int64
-	failedItems  []int64
-	finishNum    int
+	mutex       sync.Mutex
+	config      *MeterConfig
+	id          int
+	label       string
+	children    int
+	start       time.Time
+	origin      time.Time
+	finish      time.Time
+	lastStart   time.Time
+	success     *Histogram
+	failed      *Histogram
+	corrected   *Histogram
+	phases      []*Histogram
+	statuses    map[int]int
+	errors      map[string]int
+	series      map[int64]*seriesBucket
+	checks      map[string]int
+	checkFailed int
+	finishNum   int
+	delayed     int
+	dropped     int
+	labels      map[string]*Meter
+	errPrintln  func(string)
 }
 
-func NewMeter(id int) *Meter {
+func NewMeter(id int, config *MeterConfig) *Meter {
+	now := time.Now()
+	phases := make([]*Histogram, len(phaseNames))
+	for i := range phases {
+		phases[i] = NewHistogram()
+	}
 	return &Meter{
-		id:    id,
-		start: time.Now(),
+		phases:    phases,
+		statuses:  make(map[int]int),
+		errors:    make(map[string]int),
+		series:    make(map[int64]*seriesBucket),
+		checks:    make(map[string]int),
+		labels:    make(map[string]*Meter),
+		config:    config,
+		id:        id,
+		start:     now,
+		origin:    now,
+		success:   NewHistogram(),
+		failed:    NewHistogram(),
+		corrected: NewHistogram(),
 	}
 }
 
@@ -31,35 +66,137 @@ func (meter *Meter) getClientCount() int {
 	return n + meter.children
 }
 
+func (meter *Meter) setWindow(start time.Time, finish time.Time) {
+	meter.start = start
+	meter.finish = finish
+	for _, sub := range meter.labels {
+		sub.setWindow(start, finish)
+	}
+}
+
+// labelMeter returns the meter of the requests labeled name, e.g. one
+// endpoint of a mix.
+func (meter *Meter) labelMeter(name string) *Meter {
+	sub, ok := meter.labels[name]
+	if !ok {
+		sub = NewMeter(meter.id, meter.config)
+		sub.label = name
+		sub.start = meter.start
+		sub.origin = meter.origin
+		meter.labels[name] = sub
+	}
+	return sub
+}
+
+func (meter *Meter) counts() (int, int64) {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	return meter.finishNum, meter.failed.Count()
+}
+
+func (meter *Meter) CheckFailed() int {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	return meter.checkFailed
+}
+
 func (meter *Meter) Start() {
 	meter.lastStart = time.Now()
 }
 
+// isFailed reports whether res failed to send or read, or has a status
+// matching --fail-status.
+func (meter *Meter) isFailed(res *Response) bool {
+	return res.Error != nil || len(res.ErrorClass) != 0 || meter.config.FailStatus.Match(res.StatusCode)
+}
+
 func (meter *Meter) Finish(res *Response) {
-	meter.finish = time.Now()
-	meter.finishNum += 1
-	ms := time.Since(meter.lastStart).Milliseconds()
-	if res.Error != nil {
-		meter.failedItems = append(meter.failedItems, ms)
+	meter.Record(res)
+	if meter.isFailed(res) {
 		meter.Failed(res)
 	} else {
-		meter.successItems = append(meter.successItems, ms)
 		meter.Success(res)
 	}
 }
 
+// FinishStep records res only in the meter of its label, leaving the totals
+// to the journey the step belongs to.
+func (meter *Meter) FinishStep(res *Response) {
+	meter.mutex.Lock()
+	sub := meter.labelMeter(res.Label)
+	sub.lastStart = meter.lastStart
+	sub.record(res)
+	meter.mutex.Unlock()
+	if meter.isFailed(res) {
+		meter.Failed(res)
+	} else {
+		meter.Success(res)
+	}
+}
+
+func (meter *Meter) Record(res *Response) {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	meter.record(res)
+	if len(res.Label) != 0 {
+		sub := meter.labelMeter(res.Label)
+		sub.lastStart = meter.lastStart
+		sub.record(res)
+	}
+}
+
+func (meter *Meter) record(res *Response) {
+	meter.finish = time.Now()
+	meter.finishNum += 1
+	failed := meter.isFailed(res)
+	meter.recordSeries(meter.finish, res, failed)
+	if res.StatusCode != 0 {
+		meter.statuses[res.StatusCode] += 1
+	}
+	if len(res.ErrorClass) != 0 {
+		meter.errors[res.ErrorClass] += 1
+	}
+	if len(res.CheckFailures) != 0 {
+		meter.checkFailed += 1
+		for _, name := range res.CheckFailures {
+			meter.checks[name] += 1
+		}
+	}
+	if failed {
+		meter.failed.Record(int64(res.Cost))
+	} else {
+		meter.success.Record(int64(res.Cost))
+	}
+	if res.Corrected > 0 {
+		meter.corrected.Record(int64(res.Corrected))
+	}
+	for i, phase := range res.Timing.phases() {
+		if phase > 0 {
+			meter.phases[i].Record(int64(phase))
+		}
+	}
+}
+
 func (meter *Meter) Success(res *Response) {
-	fmt.Println(res.String())
+	fmt.Println(res.Format(meter.config.Unit))
 }
 
 func (meter *Meter) Failed(res *Response) {
-	ErrPrintln(res.String())
+	if meter.errPrintln != nil {
+		meter.errPrintln(res.format(meter.config.Unit, true))
+	} else {
+		ErrPrintln(res.format(meter.config.Unit, true))
+	}
 }
 
 func (meter *Meter) Extend(other *Meter) {
 	if other == nil || other.finishNum == 0 {
 		return
 	}
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	other.mutex.Lock()
+	defer other.mutex.Unlock()
 	meter.children += other.getClientCount()
 	if other.start.Before(meter.start) {
 		meter.start = other.start
@@ -68,34 +205,122 @@ func (meter *Meter) Extend(other *Meter) {
 		meter.finish = other.finish
 	}
 	meter.finishNum += other.finishNum
-	meter.successItems = append(meter.successItems, other.successItems...)
-	meter.failedItems = append(meter.failedItems, other.failedItems...)
+	meter.delayed += other.delayed
+	meter.dropped += other.dropped
+	meter.success.Merge(other.success)
+	meter.failed.Merge(other.failed)
+	meter.corrected.Merge(other.corrected)
+	for i, phase := range other.phases {
+		meter.phases[i].Merge(phase)
+	}
+	meter.extendSeries(other)
+	for code, n := range other.statuses {
+		meter.statuses[code] += n
+	}
+	for class, n := range other.errors {
+		meter.errors[class] += n
+	}
+	meter.checkFailed += other.checkFailed
+	for name, n := range other.checks {
+		meter.checks[name] += n
+	}
+	for name, sub := range other.labels {
+		meter.labelMeter(name).Extend(sub)
+	}
+}
+
+func (meter *Meter) title() string {
+	if len(meter.label) != 0 {
+		return meter.label
+	}
+	return fmt.Sprintf("client%v", meter.id)
 }
 
 func (meter *Meter) Summary() {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
 	if meter.finishNum == 0 {
 		return
 	}
-	successCost := sum(meter.successItems)
-	minSuccessCost := minWithDefault(0, meter.successItems...)
-	maxSuccessCost := maxWithDefault(0, meter.successItems...)
-	failedCost := sum(meter.failedItems)
-	minFailedCost := minWithDefault(0, meter.failedItems...)
-	maxFailedCost := maxWithDefault(0, meter.failedItems...)
-
-	costMs := meter.finish.Sub(meter.start).Milliseconds()
-
-	ErrPrintf("client%v: (%v clients real cost %vms process %v request qps %.2f\n", meter.id,
-		meter.getClientCount(), costMs, meter.finishNum, div(int64(meter.finishNum)*1000, costMs))
-
-	ErrPrintf("    all cost %vms process %v request averagy %vms max %vms min %vms\n",
-		successCost+failedCost, meter.finishNum, div(successCost+failedCost, int64(meter.finishNum)),
-		max(maxSuccessCost, maxFailedCost), min(minSuccessCost, minFailedCost))
-	ErrPrintf("    success cost %vms process %v request averagy %vms max %vms min %vms\n",
-		successCost, len(meter.successItems), div(successCost, int64(len(meter.successItems))),
-		maxSuccessCost, minSuccessCost)
-	ErrPrintf("    failed cost %vms process %v request averagy %vms max %vms min %vms\n",
-		failedCost, len(meter.failedItems), div(failedCost, int64(len(meter.failedItems))),
-		maxFailedCost, minFailedCost)
+	all := NewHistogram()
+	all.Merge(meter.success)
+	all.Merge(meter.failed)
+
+	unit := meter.config.Unit
+	cost := meter.finish.Sub(meter.start)
+
+	ErrPrintf("%v: (%v clients real cost %v process %v request qps %.2f\n", meter.title(),
+		meter.getClientCount(), unit.Format(cost), meter.finishNum, float64(meter.finishNum)/cost.Seconds())
+
+	printHistogram(unit, "all", all)
+	printHistogram(unit, "success", meter.success)
+	printHistogram(unit, "failed", meter.failed)
+	ErrPrintf("    percentile p50 %v p90 %v p95 %v p99 %v p99.9 %v p99.99 %v\n",
+		unit.Format(percentile(all, 50)), unit.Format(percentile(all, 90)), unit.Format(percentile(all, 95)),
+		unit.Format(percentile(all, 99)), unit.Format(percentile(all, 99.9)), unit.Format(percentile(all, 99.99)))
+	if corrected := meter.corrected; corrected.Count() != 0 {
+		ErrPrintf("    corrected process %v request averagy %v p50 %v p90 %v p99 %v p99.9 %v max %v\n", corrected.Count(),
+			unit.Format(time.Duration(corrected.Mean())), unit.Format(percentile(corrected, 50)),
+			unit.Format(percentile(corrected, 90)), unit.Format(percentile(corrected, 99)),
+			unit.Format(percentile(corrected, 99.9)), unit.Format(time.Duration(corrected.Max())))
+	}
+	if len(meter.statuses) != 0 {
+		classes := make(map[string]int)
+		var codes []string
+		for _, code := range slices.Sorted(maps.Keys(meter.statuses)) {
+			classes[fmt.Sprintf("%vxx", code/100)] += meter.statuses[code]
+			codes = append(codes, fmt.Sprintf("%v %v", code, meter.statuses[code]))
+		}
+		var counts []string
+		for _, class := range slices.Sorted(maps.Keys(classes)) {
+			counts = append(counts, fmt.Sprintf("%v %v", class, classes[class]))
+		}
+		ErrPrintf("    status %v (%v)\n", strings.Join(counts, " "), strings.Join(codes, " "))
+	}
+	if len(meter.errors) != 0 {
+		var counts []string
+		for _, class := range slices.Sorted(maps.Keys(meter.errors)) {
+			counts = append(counts, fmt.Sprintf("%v %v", class, meter.errors[class]))
+		}
+		ErrPrintf("    errors %v\n", strings.Join(counts, " "))
+	}
+	if meter.checkFailed != 0 {
+		var counts []string
+		for _, name := range slices.Sorted(maps.Keys(meter.checks)) {
+			counts = append(counts, fmt.Sprintf("[%v] %v", name, meter.checks[name]))
+		}
+		ErrPrintf("    checks failed %v request %v\n", meter.checkFailed, strings.Join(counts, " "))
+	}
+	for i, phase := range meter.phases {
+		if phase.Count() != 0 {
+			ErrPrintf("    %v process %v request averagy %v p50 %v p99 %v max %v\n", phaseNames[i], phase.Count(),
+				unit.Format(time.Duration(phase.Mean())), unit.Format(percentile(phase, 50)),
+				unit.Format(percentile(phase, 99)), unit.Format(time.Duration(phase.Max())))
+		}
+	}
+	if meter.delayed != 0 || meter.dropped != 0 {
+		ErrPrintf("    rate delayed %v request dropped %v request\n", meter.delayed, meter.dropped)
+	}
+	for _, name := range slices.Sorted(maps.Keys(meter.labels)) {
+		sub := meter.labels[name]
+		all := NewHistogram()
+		all.Merge(sub.success)
+		all.Merge(sub.failed)
+		ErrPrintf("    [%v] process %v request qps %.2f failed %v averagy %v p50 %v p99 %v max %v\n", name,
+			sub.finishNum, float64(sub.finishNum)/cost.Seconds(), sub.failed.Count(),
+			unit.Format(time.Duration(all.Mean())), unit.Format(percentile(all, 50)),
+			unit.Format(percentile(all, 99)), unit.Format(time.Duration(all.Max())))
+	}
 	ErrPrintln("")
 }
+
+func percentile(histogram *Histogram, p float64) time.Duration {
+	return time.Duration(histogram.Percentile(p))
+}
+
+func printHistogram(unit Unit, name string, histogram *Histogram) {
+	ErrPrintf("    %v cost %v process %v request averagy %v max %v min %v\n",
+		name, unit.Format(time.Duration(histogram.Sum())), histogram.Count(),
+		unit.Format(time.Duration(histogram.Mean())), unit.Format(time.Duration(histogram.Max())),
+		unit.Format(time.Duration(histogram.Min())))
+}
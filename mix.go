@@ -0,0 +1,152 @@
+package gmeter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+)
+
+// EndpointConfig is one named request definition of a weighted mix.
+type EndpointConfig struct {
+	Name         string   `json:"name"`
+	Weight       float64  `json:"weight"`
+	Method       string   `json:"method"`
+	Url          string   `json:"url"`
+	UrlsPath     string   `json:"urls_path"`
+	RequestsPath string   `json:"requests_path"`
+	Headers      []string `json:"headers"`
+	Body         string   `json:"body"`
+	BodyPath     string   `json:"body_path"`
+	BodiesPath   string   `json:"bodies_path"`
+}
+
+// ReadEndpoints reads a json list of endpoints, weights defaulting to 1.
+func ReadEndpoints(path string) ([]*EndpointConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []*EndpointConfig
+	if err := json.Unmarshal(content, &endpoints); err != nil {
+		return nil, fmt.Errorf("mix %v: %v", path, err)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("mix %v: no endpoints", path)
+	}
+	for i, endpoint := range endpoints {
+		if len(endpoint.Name) == 0 {
+			endpoint.Name = fmt.Sprintf("endpoint%v", i+1)
+		}
+		if endpoint.Weight == 0 {
+			endpoint.Weight = 1
+		}
+		if endpoint.Weight < 0 {
+			return nil, fmt.Errorf("mix %v: endpoint %v has a negative weight", path, endpoint.Name)
+		}
+	}
+	return endpoints, nil
+}
+
+// requestConfig derives the generator config of the endpoint, inheriting the
+// method, headers and feeder of base.
+func (endpoint *EndpointConfig) requestConfig(base *RequestGeneratorConfig) *RequestGeneratorConfig {
+	config := &RequestGeneratorConfig{
+		Headers:      append(append([]string{}, base.Headers...), endpoint.Headers...),
+		Method:       base.Method,
+		Feeder:       base.Feeder,
+		Url:          endpoint.Url,
+		UrlsPath:     endpoint.UrlsPath,
+		RequestsPath: endpoint.RequestsPath,
+		Body:         endpoint.Body,
+		BodyPath:     endpoint.BodyPath,
+		BodiesPath:   endpoint.BodiesPath,
+	}
+	if len(endpoint.Method) != 0 {
+		config.Method = strings.ToUpper(endpoint.Method)
+	}
+	return config
+}
+
+type mixEntry struct {
+	name      string
+	weight    float64
+	generator *RequestGenerator
+}
+
+// checkMix rejects the sources and bodies of config that the endpoints of a
+// mix would silently replace.
+func checkMix(config *RequestGeneratorConfig) error {
+	for _, option := range [][2]string{
+		{"url", config.Url}, {"urls-path", config.UrlsPath}, {"requests-path", config.RequestsPath},
+		{"curls-path", config.CurlsPath}, {"scenario", config.ScenarioPath}, {"har", config.Har.Path},
+		{"body", config.Body}, {"body-path", config.BodyPath}, {"bodies-path", config.BodiesPath},
+		{"extra-json-path", config.ExtraJsonPath},
+	} {
+		if len(option[1]) != 0 {
+			return fmt.Errorf("mix sets the requests of its endpoints, remove %v", option[0])
+		}
+	}
+	return nil
+}
+
+// newMix builds one generator per endpoint, all taking rows from the feeder
+// of the run so each request uses up one row whichever endpoint it hits.
+func newMix(config *RequestGeneratorConfig, env *templateEnv) ([]*mixEntry, error) {
+	if err := checkMix(config); err != nil {
+		return nil, err
+	}
+	endpoints, err := ReadEndpoints(config.MixPath)
+	if err != nil {
+		return nil, err
+	}
+	var mix []*mixEntry
+	for _, endpoint := range endpoints {
+		generator, err := newRequestGenerator(endpoint.requestConfig(config), env)
+		if err != nil {
+			for _, entry := range mix {
+				entry.generator.Close()
+			}
+			return nil, fmt.Errorf("endpoint %v: %v", endpoint.Name, err)
+		}
+		mix = append(mix, &mixEntry{
+			name:      endpoint.Name,
+			weight:    endpoint.Weight,
+			generator: generator,
+		})
+	}
+	return mix, nil
+}
+
+// generateMix picks an endpoint by weight, dropping endpoints whose sources
+// run out until none is left.
+func (generator *RequestGenerator) generateMix() (*Request, error) {
+	for len(generator.mix) != 0 {
+		total := 0.0
+		for _, entry := range generator.mix {
+			total += entry.weight
+		}
+		pick := rand.Float64() * total
+		i := 0
+		for ; i < len(generator.mix)-1; i++ {
+			if pick -= generator.mix[i].weight; pick < 0 {
+				break
+			}
+		}
+		entry := generator.mix[i]
+		request, err := entry.generator.Generate()
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %v: %v", entry.name, err)
+		}
+		if request == nil {
+			generator.mix = append(generator.mix[:i], generator.mix[i+1:]...)
+			generator.closed = append(generator.closed, entry)
+			continue
+		}
+		request.ID = generator.index
+		request.Label = entry.name
+		return request, nil
+	}
+	return nil, nil
+}
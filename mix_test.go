@@ -0,0 +1,46 @@
+package gmeter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMixFeeder(t *testing.T) {
+	config := &RequestGeneratorConfig{
+		Method:  "GET",
+		MixPath: writeTestFile(t, "mix.json", `[{"name":"a","url":"http://a/?u={{.user}}"},{"name":"b","url":"http://b/?u={{.user}}"}]`),
+		Feeder:  FeederConfig{Path: writeTestFile(t, "users.csv", "user\nu1\nu2\nu3\n")},
+	}
+	generator, err := NewRequestGenerator(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer generator.Close()
+	var users []string
+	for {
+		request, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		} else if request == nil {
+			break
+		}
+		if host := request.Req.URL.Host; host != request.Label {
+			t.Fatalf("request to %v labeled %v", host, request.Label)
+		}
+		users = append(users, request.Req.URL.Query().Get("u"))
+	}
+	if got := strings.Join(users, ","); got != "u1,u2,u3" {
+		t.Fatalf("got users %v, want u1,u2,u3", got)
+	}
+}
+
+func TestMixRejectsSources(t *testing.T) {
+	config := &RequestGeneratorConfig{
+		Method:  "GET",
+		Url:     "http://a/",
+		MixPath: writeTestFile(t, "mix.json", `[{"url":"http://a/"}]`),
+	}
+	if _, err := NewRequestGenerator(config); err == nil || !strings.Contains(err.Error(), "remove url") {
+		t.Fatalf("got error %v, want the ignored url rejected", err)
+	}
+}
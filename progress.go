@@ -0,0 +1,143 @@
+package gmeter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type progress struct {
+	mutex    sync.Mutex
+	driver   *Driver
+	interval time.Duration
+	tty      bool
+	window   *Histogram
+	inflight atomic.Int64
+	last     int
+	out      sync.Mutex
+	block    string
+	lines    int
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+func newProgress(driver *Driver, interval time.Duration) *progress {
+	return &progress{
+		driver:   driver,
+		interval: interval,
+		tty:      isTerminal(os.Stderr),
+		window:   NewHistogram(),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (progress *progress) record(res *Response) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	progress.window.Record(int64(res.Cost))
+}
+
+func (progress *progress) start() {
+	go func() {
+		defer close(progress.stopped)
+		ticker := time.NewTicker(progress.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-progress.done:
+				return
+			case <-ticker.C:
+				progress.render()
+			}
+		}
+	}()
+}
+
+func (progress *progress) stop() {
+	close(progress.done)
+	<-progress.stopped
+	progress.out.Lock()
+	defer progress.out.Unlock()
+	if progress.tty && progress.lines != 0 {
+		ErrPrintln("")
+	}
+}
+
+func (progress *progress) render() {
+	driver := progress.driver
+	completed, failed := 0, int64(0)
+	for _, client := range driver.liveClients() {
+		n, f := client.meter.counts()
+		completed += n
+		failed += f
+	}
+	progress.mutex.Lock()
+	window := progress.window
+	progress.window = NewHistogram()
+	progress.mutex.Unlock()
+
+	unit := driver.config.MeterConfig.Unit
+	elapsed := time.Since(driver.start).Truncate(100 * time.Millisecond)
+	var total string
+	if !driver.deadline.IsZero() {
+		total = fmt.Sprintf("elapsed %v/%v", elapsed, driver.deadline.Sub(driver.start).Truncate(100*time.Millisecond))
+	} else {
+		total = fmt.Sprintf("elapsed %v", elapsed)
+	}
+	var done string
+	if driver.deadline.IsZero() {
+		done = fmt.Sprintf("completed %v/%v", completed, driver.config.Concurrency*driver.config.ClientConfig.Count)
+	} else {
+		done = fmt.Sprintf("completed %v", completed)
+	}
+	rps := float64(completed-progress.last) / progress.interval.Seconds()
+	progress.last = completed
+	items := []string{
+		total,
+		done,
+		fmt.Sprintf("rps %.2f", rps),
+		fmt.Sprintf("errors %.2f%%", div(failed*100, int64(completed))),
+		fmt.Sprintf("inflight %v", progress.inflight.Load()),
+		fmt.Sprintf("p50 %v p99 %v", unit.Format(percentile(window, 50)), unit.Format(percentile(window, 99))),
+	}
+	if !progress.tty {
+		ErrPrintln(strings.Join(items, " "))
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "gmeter %v\n", time.Now().Format(time.TimeOnly))
+	for _, item := range items {
+		fmt.Fprintf(&b, "  %v\n", item)
+	}
+	progress.out.Lock()
+	defer progress.out.Unlock()
+	progress.clear()
+	progress.block = b.String()
+	progress.lines = len(items) + 1
+	ErrPrintf("%v", progress.block)
+}
+
+// clear erases the block drawn last, the caller holding out.
+func (progress *progress) clear() {
+	if progress.lines != 0 {
+		ErrPrintf("\033[%vA\033[J", progress.lines)
+	}
+}
+
+// println writes a line to stderr above the block, which is drawn again so
+// the next render does not erase the line.
+func (progress *progress) println(s string) {
+	if !progress.tty {
+		ErrPrintln(s)
+		return
+	}
+	progress.out.Lock()
+	defer progress.out.Unlock()
+	progress.clear()
+	ErrPrintln(s)
+	ErrPrintf("%v", progress.block)
+}
@@ -0,0 +1,268 @@
+package gmeter
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type LatencyReport struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean_ms"`
+	Min   float64 `json:"min_ms"`
+	Max   float64 `json:"max_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+	P999  float64 `json:"p99_9_ms"`
+	P9999 float64 `json:"p99_99_ms"`
+}
+
+type MeterReport struct {
+	Name           string         `json:"name"`
+	Clients        int            `json:"clients"`
+	Start          time.Time      `json:"start"`
+	End            time.Time      `json:"end"`
+	Requests       int            `json:"requests"`
+	Success        int64          `json:"success"`
+	Failed         int64          `json:"failed"`
+	QPS            float64        `json:"qps"`
+	Latency        *LatencyReport `json:"latency"`
+	SuccessLatency *LatencyReport `json:"success_latency"`
+	FailedLatency  *LatencyReport `json:"failed_latency"`
+	// CorrectedLatency runs from the scheduled send time of each request, so
+	// it includes the time spent queued behind a stalled server.
+	CorrectedLatency *LatencyReport            `json:"corrected_latency,omitempty"`
+	Phases           map[string]*LatencyReport `json:"phases,omitempty"`
+	StatusClasses    map[string]int            `json:"status_classes,omitempty"`
+	Statuses         map[string]int            `json:"statuses,omitempty"`
+	Errors           map[string]int            `json:"errors,omitempty"`
+	CheckFailed      int                       `json:"check_failed"`
+	Checks           map[string]int            `json:"checks,omitempty"`
+	Delayed          int                       `json:"delayed,omitempty"`
+	Dropped          int                       `json:"dropped,omitempty"`
+	Labels           []*MeterReport            `json:"labels,omitempty"`
+}
+
+type StageConfigReport struct {
+	Duration    float64 `json:"duration_ms"`
+	Concurrency int     `json:"concurrency,omitempty"`
+	Rate        float64 `json:"rate,omitempty"`
+	Shape       string  `json:"shape"`
+}
+
+type ThinkTimeReport struct {
+	Kind   string  `json:"kind"`
+	Min    float64 `json:"min_ms,omitempty"`
+	Max    float64 `json:"max_ms,omitempty"`
+	Mean   float64 `json:"mean_ms,omitempty"`
+	StdDev float64 `json:"stddev_ms,omitempty"`
+}
+
+type CheckConfigReport struct {
+	Status     string   `json:"status,omitempty"`
+	BodyRegex  string   `json:"body_regex,omitempty"`
+	JsonPaths  []string `json:"json_paths,omitempty"`
+	Headers    []string `json:"headers,omitempty"`
+	MaxLatency float64  `json:"max_latency_ms,omitempty"`
+}
+
+type ClientConfigReport struct {
+	Count          int                `json:"count"`
+	Proxy          string             `json:"proxy,omitempty"`
+	Timeout        float64            `json:"timeout_ms,omitempty"`
+	ConnectTimeout float64            `json:"connect_timeout_ms,omitempty"`
+	TLSTimeout     float64            `json:"tls_timeout_ms,omitempty"`
+	HeaderTimeout  float64            `json:"header_timeout_ms,omitempty"`
+	IdleTimeout    float64            `json:"idle_timeout_ms,omitempty"`
+	Checks         *CheckConfigReport `json:"checks"`
+	Session        SessionConfig      `json:"session"`
+	Think          *ThinkTimeReport   `json:"think,omitempty"`
+	Pacing         float64            `json:"pacing_ms,omitempty"`
+}
+
+type MeterConfigReport struct {
+	Unit           Unit        `json:"unit"`
+	FailStatus     *StatusRule `json:"fail_status"`
+	SeriesInterval float64     `json:"series_interval_ms"`
+}
+
+// ConfigReport is the DriverConfig of a run in the schema of the report.
+type ConfigReport struct {
+	Concurrency      int                     `json:"concurrency"`
+	Skip             int                     `json:"skip,omitempty"`
+	SkipError        bool                    `json:"skip_error,omitempty"`
+	Rate             float64                 `json:"rate,omitempty"`
+	RateDrop         bool                    `json:"rate_drop,omitempty"`
+	Duration         float64                 `json:"duration_ms,omitempty"`
+	Stages           []*StageConfigReport    `json:"stages,omitempty"`
+	ReportJsonPath   string                  `json:"report_json_path,omitempty"`
+	SeriesPath       string                  `json:"series_path,omitempty"`
+	ProgressInterval float64                 `json:"progress_interval_ms"`
+	StopTimeout      float64                 `json:"stop_timeout_ms"`
+	Thresholds       []*Threshold            `json:"thresholds,omitempty"`
+	Client           *ClientConfigReport     `json:"client"`
+	Meter            *MeterConfigReport      `json:"meter"`
+	Request          *RequestGeneratorConfig `json:"request"`
+}
+
+type Report struct {
+	Config  *ConfigReport  `json:"config"`
+	Summary *MeterReport   `json:"summary"`
+	Clients []*MeterReport `json:"clients"`
+	Stages  []*MeterReport `json:"stages,omitempty"`
+}
+
+func milliseconds(value int64) float64 {
+	return float64(value) / float64(time.Millisecond)
+}
+
+func durationMilliseconds(d time.Duration) float64 {
+	return milliseconds(int64(d))
+}
+
+func NewConfigReport(config *DriverConfig) *ConfigReport {
+	client := &config.ClientConfig
+	report := &ConfigReport{
+		Concurrency:      config.Concurrency,
+		Skip:             config.Skip,
+		SkipError:        config.SkipError,
+		Rate:             config.Rate,
+		RateDrop:         config.RateDrop,
+		Duration:         durationMilliseconds(config.Duration),
+		ReportJsonPath:   config.ReportJsonPath,
+		SeriesPath:       config.SeriesPath,
+		ProgressInterval: durationMilliseconds(config.ProgressInterval),
+		StopTimeout:      durationMilliseconds(config.StopTimeout),
+		Thresholds:       config.Thresholds,
+		Client: &ClientConfigReport{
+			Count:          client.Count,
+			Proxy:          client.Proxy,
+			Timeout:        durationMilliseconds(client.Timeout),
+			ConnectTimeout: durationMilliseconds(client.ConnectTimeout),
+			TLSTimeout:     durationMilliseconds(client.TLSTimeout),
+			HeaderTimeout:  durationMilliseconds(client.HeaderTimeout),
+			IdleTimeout:    durationMilliseconds(client.IdleTimeout),
+			Checks: &CheckConfigReport{
+				Status:     client.Checks.Status,
+				BodyRegex:  client.Checks.BodyRegex,
+				JsonPaths:  client.Checks.JsonPaths,
+				Headers:    client.Checks.Headers,
+				MaxLatency: durationMilliseconds(client.Checks.MaxLatency),
+			},
+			Session: client.Session,
+			Pacing:  durationMilliseconds(client.Pacing),
+		},
+		Meter: &MeterConfigReport{
+			Unit:           config.MeterConfig.Unit,
+			FailStatus:     config.MeterConfig.FailStatus,
+			SeriesInterval: durationMilliseconds(config.MeterConfig.SeriesInterval),
+		},
+		Request: &config.RequestGeneratorConfig,
+	}
+	for _, stage := range config.Stages {
+		report.Stages = append(report.Stages, &StageConfigReport{
+			Duration:    durationMilliseconds(stage.Duration),
+			Concurrency: stage.Concurrency,
+			Rate:        stage.Rate,
+			Shape:       stage.Shape,
+		})
+	}
+	if think := client.Think; think != nil {
+		report.Client.Think = &ThinkTimeReport{
+			Kind:   think.Kind,
+			Min:    durationMilliseconds(think.Min),
+			Max:    durationMilliseconds(think.Max),
+			Mean:   durationMilliseconds(think.Mean),
+			StdDev: durationMilliseconds(think.StdDev),
+		}
+	}
+	return report
+}
+
+func NewLatencyReport(histogram *Histogram) *LatencyReport {
+	return &LatencyReport{
+		Count: histogram.Count(),
+		Mean:  histogram.Mean() / float64(time.Millisecond),
+		Min:   milliseconds(histogram.Min()),
+		Max:   milliseconds(histogram.Max()),
+		P50:   milliseconds(histogram.Percentile(50)),
+		P90:   milliseconds(histogram.Percentile(90)),
+		P95:   milliseconds(histogram.Percentile(95)),
+		P99:   milliseconds(histogram.Percentile(99)),
+		P999:  milliseconds(histogram.Percentile(99.9)),
+		P9999: milliseconds(histogram.Percentile(99.99)),
+	}
+}
+
+func (meter *Meter) Report() *MeterReport {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	all := NewHistogram()
+	all.Merge(meter.success)
+	all.Merge(meter.failed)
+	report := &MeterReport{
+		Name:           meter.title(),
+		Clients:        meter.getClientCount(),
+		Start:          meter.start,
+		End:            meter.finish,
+		Requests:       meter.finishNum,
+		Success:        meter.success.Count(),
+		Failed:         meter.failed.Count(),
+		Latency:        NewLatencyReport(all),
+		SuccessLatency: NewLatencyReport(meter.success),
+		FailedLatency:  NewLatencyReport(meter.failed),
+		Phases:         make(map[string]*LatencyReport),
+		StatusClasses:  make(map[string]int),
+		Statuses:       make(map[string]int),
+		Errors:         make(map[string]int),
+		CheckFailed:    meter.checkFailed,
+		Checks:         make(map[string]int),
+		Delayed:        meter.delayed,
+		Dropped:        meter.dropped,
+	}
+	if meter.corrected.Count() != 0 {
+		report.CorrectedLatency = NewLatencyReport(meter.corrected)
+	}
+	if cost := meter.finish.Sub(meter.start); cost > 0 {
+		report.QPS = float64(meter.finishNum) / cost.Seconds()
+	}
+	for i, phase := range meter.phases {
+		if phase.Count() != 0 {
+			report.Phases[phaseNames[i]] = NewLatencyReport(phase)
+		}
+	}
+	for code, n := range meter.statuses {
+		report.StatusClasses[fmt.Sprintf("%vxx", code/100)] += n
+		report.Statuses[strconv.Itoa(code)] = n
+	}
+	for class, n := range meter.errors {
+		report.Errors[class] = n
+	}
+	for name, n := range meter.checks {
+		report.Checks[name] = n
+	}
+	for _, name := range slices.Sorted(maps.Keys(meter.labels)) {
+		report.Labels = append(report.Labels, meter.labels[name].Report())
+	}
+	return report
+}
+
+// WriteReport writes report as indented JSON to path, "-" meaning stdout.
+func WriteReport(path string, report *Report) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
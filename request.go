@@ -1,8 +1,19 @@
 package gmeter
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type Request struct {
-	ID  int
-	Req *http.Request
+	ID    int
+	Label string
+	Req   *http.Request
+	At    time.Duration
+	// Intended is when the schedule meant the request to be sent, zero when
+	// requests are sent as fast as clients free up.
+	Intended time.Time
+	prepare  func(client *Client) error
+	journey  *Journey
+	row      map[string]string
 }
@@ -1,12 +1,14 @@
 package gmeter
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"golang.org/x/net/html/charset"
@@ -17,82 +19,140 @@ type Response struct {
 	ResponseUrl      string
 	ResponseMimeType string
 	Error            error
+	ErrorClass       string
+	Timeout          bool
 	StatusCode       int
+	Header           http.Header
 	Body             any
 	BodyError        error
-	Cost             int64
+	Cost             time.Duration
+	Corrected        time.Duration
+	Timing           Timing
 	ID               int
+	Label            string
+	CheckFailures    []string
 }
 
 func NewResponse(request *Request, response *http.Response, err error) *Response {
 	res := &Response{
 		Error:      err,
-		RequestUrl: request.Req.URL.String(),
+		ErrorClass: ClassifyError(err),
 		ID:         request.ID,
+		Label:      request.Label,
+	}
+	if request.Req != nil {
+		res.RequestUrl = request.Req.URL.String()
 	}
 	if response != nil {
 		defer response.Body.Close()
 		res.StatusCode = response.StatusCode
+		res.Header = response.Header
 		res.ResponseUrl = response.Request.URL.String()
-		contentType := response.Header.Get("Content-Type")
-		mediatype, _, _ := mime.ParseMediaType(contentType)
-		if strings.HasPrefix(mediatype, "text/") {
-			if body, err := charset.NewReader(response.Body, contentType); err != nil {
-				res.BodyError = err
-			} else {
-				content, err := io.ReadAll(body)
-				if err != nil {
-					res.BodyError = err
-				} else {
-					res.Body = string(content)
-				}
-			}
-		} else if mediatype == "application/json" {
-			result := make(map[string]any)
-			if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
-				res.BodyError = err
-			} else {
-				res.Body = result
+		if content, err := io.ReadAll(response.Body); err != nil {
+			res.BodyError = err
+			res.ErrorClass = ErrorBodyRead
+			if ClassifyError(err) == ErrorTimeout {
+				res.ErrorClass = ErrorTimeout
 			}
 		} else {
-			if body, err := io.ReadAll(response.Body); err != nil {
-				res.BodyError = err
-			} else {
-				if utf8.Valid(body) {
-					res.Body = string(body)
-				} else {
-					res.Body = body
-				}
-			}
+			res.Body, res.BodyError = decodeBody(response.Header.Get("Content-Type"), content)
 		}
 	}
+	res.Timeout = res.ErrorClass == ErrorTimeout
 	return res
 }
 
-func (res *Response) DefaultJson() map[string]any {
+// decodeBody decodes a fully read body by its content type. A body that does
+// not decode is kept as text with the decode error, which is not a failure.
+func decodeBody(contentType string, content []byte) (any, error) {
+	mediatype, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mediatype, "text/") {
+		body, err := charset.NewReader(bytes.NewReader(content), contentType)
+		if err != nil {
+			return string(content), err
+		}
+		if decoded, err := io.ReadAll(body); err != nil {
+			return string(content), err
+		} else {
+			return string(decoded), nil
+		}
+	} else if mediatype == "application/json" {
+		if len(bytes.TrimSpace(content)) == 0 {
+			return nil, nil
+		}
+		result := make(map[string]any)
+		if err := json.Unmarshal(content, &result); err != nil {
+			return string(content), err
+		}
+		return result, nil
+	} else if utf8.Valid(content) {
+		return string(content), nil
+	}
+	return content, nil
+}
+
+func (res *Response) BodyString() string {
+	switch body := res.Body.(type) {
+	case nil:
+		return ""
+	case string:
+		return body
+	case []byte:
+		return string(body)
+	default:
+		b, _ := json.Marshal(body)
+		return string(b)
+	}
+}
+
+func (res *Response) DefaultJson(unit Unit) map[string]any {
 	result := make(map[string]any)
 	result["url"] = res.RequestUrl
-	result["cost"] = res.Cost
+	cost, unit := unit.Value(res.Cost)
+	result["cost"] = cost
+	result["cost_unit"] = unit
+	if res.Corrected > 0 {
+		result["corrected"], _ = unit.Value(res.Corrected)
+	}
+	timing := make(map[string]any)
+	for i, phase := range res.Timing.phases() {
+		if phase > 0 {
+			timing[phaseNames[i]], _ = unit.Value(phase)
+		}
+	}
+	if len(timing) != 0 {
+		result["timing"] = timing
+	}
+	if res.Timeout {
+		result["timeout"] = true
+	}
+	if len(res.CheckFailures) != 0 {
+		result["check_failures"] = res.CheckFailures
+	}
 	result["id"] = res.ID
+	if len(res.Label) != 0 {
+		result["label"] = res.Label
+	}
 	return result
 }
 
-func (res *Response) ErrorJson() (map[string]any, error) {
+func (res *Response) ErrorJson(unit Unit) (map[string]any, error) {
 	if res.Error == nil {
 		return nil, fmt.Errorf("Response error is nil")
 	} else {
-		result := res.DefaultJson()
+		result := res.DefaultJson(unit)
 		result["code"] = 1
 		result["error"] = res.Error.Error()
+		result["error_class"] = res.ErrorClass
 		return result, nil
 	}
 }
 
-func (res *Response) SuccessJson() (map[string]any, error) {
+func (res *Response) SuccessJson(unit Unit) (map[string]any, error) {
 	if res.Error != nil {
 		return nil, fmt.Errorf("Response error is not nil")
 	} else {
-		result := res.DefaultJson()
+		result := res.DefaultJson(unit)
 		result["code"] = 0
 		result["response_url"] = res.ResponseUrl
 		result["status_code"] = res.StatusCode
@@ -105,12 +165,26 @@ func (res *Response) SuccessJson() (map[string]any, error) {
 }
 
 func (res *Response) String() string {
+	return res.Format(UnitAuto)
+}
+
+func (res *Response) Format(unit Unit) string {
+	return res.format(unit, res.Error != nil)
+}
+
+// format formats res as failed, with code 1, when failed is set even though
+// the request itself succeeded, e.g. for a status matching --fail-status or a
+// truncated body.
+func (res *Response) format(unit Unit, failed bool) string {
 	var result map[string]any
 	var err error
 	if res.Error != nil {
-		result, err = res.ErrorJson()
-	} else {
-		result, err = res.SuccessJson()
+		result, err = res.ErrorJson(unit)
+	} else if result, err = res.SuccessJson(unit); err == nil && failed {
+		result["code"] = 1
+		if len(res.ErrorClass) != 0 {
+			result["error_class"] = res.ErrorClass
+		}
 	}
 	if err == nil {
 		var s []byte
@@ -118,7 +192,7 @@ func (res *Response) String() string {
 			return string(s)
 		}
 	}
-	result = res.DefaultJson()
+	result = res.DefaultJson(unit)
 	result["code"] = 1
 	result["error"] = err.Error()
 	if s, err := json.Marshal(result); err == nil {
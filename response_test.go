@@ -0,0 +1,63 @@
+package gmeter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type truncatedReader struct {
+	io.Reader
+}
+
+func (r truncatedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		err = errors.New("read: connection reset by peer")
+	}
+	return n, err
+}
+
+func TestNewResponseBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        io.Reader
+		want        string
+		bodyError   bool
+		errorClass  string
+	}{
+		{name: "json", contentType: "application/json", body: strings.NewReader(`{"a":1}`), want: `{"a":1}`},
+		{name: "empty json", contentType: "application/json", body: strings.NewReader("")},
+		{name: "malformed json", contentType: "application/json", body: strings.NewReader(`{"a":`), want: `{"a":`, bodyError: true},
+		{name: "text", contentType: "text/plain; charset=utf-8", body: strings.NewReader("ok"), want: "ok"},
+		{name: "binary", contentType: "application/octet-stream", body: strings.NewReader("ok"), want: "ok"},
+		{name: "truncated", contentType: "application/json", body: truncatedReader{strings.NewReader(`{"a":`)},
+			bodyError: true, errorClass: ErrorBodyRead},
+	}
+	meter := NewMeter(0, &MeterConfig{})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://a", nil)
+			response := &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": {test.contentType}},
+				Body:       io.NopCloser(test.body),
+				Request:    req,
+			}
+			res := NewResponse(&Request{Req: req}, response, nil)
+			if got := res.BodyString(); got != test.want {
+				t.Fatalf("got body %q, want %q", got, test.want)
+			}
+			if (res.BodyError != nil) != test.bodyError || res.ErrorClass != test.errorClass {
+				t.Fatalf("got body error %v class %q, want error %v class %q", res.BodyError, res.ErrorClass,
+					test.bodyError, test.errorClass)
+			}
+			if failed := meter.isFailed(res); failed != (len(test.errorClass) != 0) {
+				t.Fatalf("got failed %v for error class %q", failed, res.ErrorClass)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package gmeter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	SearchConcurrency = "concurrency"
+	SearchRate        = "rate"
+)
+
+// SearchConfig steps concurrency or rate up from Start by Step until a step
+// crosses MaxP99 or MaxErrorRate, gains less than MinGain of the throughput
+// gain linear scaling from the best step so far would give, falls short of
+// its rate by more than RateTolerance, or reaches Max. A rate search sends
+// from a fixed pool of Clients.
+type SearchConfig struct {
+	Mode          string
+	Start         float64
+	Step          float64
+	Max           float64
+	Clients       int
+	MaxP99        time.Duration
+	MaxErrorRate  float64
+	MinGain       float64
+	RateTolerance float64
+}
+
+type SearchStep struct {
+	Target    float64
+	Requests  int
+	QPS       float64
+	P99       time.Duration
+	ErrorRate float64
+	Stop      string
+}
+
+func (config *SearchConfig) validate(driverConfig *DriverConfig) error {
+	if config.Mode != SearchConcurrency && config.Mode != SearchRate {
+		return fmt.Errorf("invalid search %q, want %v or %v", config.Mode, SearchConcurrency, SearchRate)
+	}
+	if driverConfig.Duration <= 0 {
+		return fmt.Errorf("search needs a duration per step")
+	}
+	if config.Mode == SearchRate && config.Clients <= 0 {
+		return fmt.Errorf("rate search needs a concurrency bounding the clients that send the rate")
+	}
+	if config.Step <= 0 {
+		return fmt.Errorf("search step must be positive")
+	}
+	if config.Start <= 0 {
+		config.Start = config.Step
+	}
+	return nil
+}
+
+func (config *SearchConfig) knee(step *SearchStep, best *SearchStep) string {
+	if config.MaxP99 > 0 && step.P99 > config.MaxP99 {
+		return fmt.Sprintf("p99 > %v", config.MaxP99)
+	}
+	if step.ErrorRate > config.MaxErrorRate {
+		return fmt.Sprintf("error rate > %.2f%%", config.MaxErrorRate*100)
+	}
+	if config.Mode == SearchRate && step.QPS < step.Target*(1-config.RateTolerance) {
+		return "qps below rate"
+	}
+	if best != nil && best.QPS > 0 {
+		linear := (step.Target - best.Target) / best.Target
+		if step.QPS/best.QPS < 1+config.MinGain*linear {
+			return fmt.Sprintf("qps gain < %.2f%% of linear", config.MinGain*100)
+		}
+	}
+	return ""
+}
+
+func (config *SearchConfig) runStep(ctx context.Context, driverConfig *DriverConfig, target float64) (*SearchStep, error) {
+	stepConfig := *driverConfig
+	stepConfig.Stages = nil
+	stepConfig.Thresholds = nil
+	stepConfig.ReportJsonPath = ""
+	stepConfig.SeriesPath = ""
+	if config.Mode == SearchRate {
+		stepConfig.Rate = target
+		stepConfig.Concurrency = config.Clients
+	} else {
+		stepConfig.Concurrency = int(target)
+	}
+	driver, err := NewDriver(&stepConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := driver.Run(ctx); err != nil {
+		ErrPrintf("search step %v: %v\n", target, err)
+	}
+	if err := driver.Close(); err != nil {
+		return nil, err
+	}
+	report := driver.meter.Report()
+	step := &SearchStep{
+		Target:   target,
+		Requests: report.Requests,
+		QPS:      report.QPS,
+		P99:      time.Duration(report.Latency.P99 * float64(time.Millisecond)),
+	}
+	if report.Requests != 0 {
+		step.ErrorRate = float64(report.Failed) / float64(report.Requests)
+	}
+	return step, nil
+}
+
+// Search runs one driver per step and returns every step with the last one
+// before the knee, nil when even the first step crossed it.
+func Search(ctx context.Context, driverConfig *DriverConfig, config *SearchConfig) ([]*SearchStep, *SearchStep, error) {
+	if err := config.validate(driverConfig); err != nil {
+		return nil, nil, err
+	}
+	var steps []*SearchStep
+	var best *SearchStep
+	for target := config.Start; config.Max <= 0 || target <= config.Max; target += config.Step {
+		step, err := config.runStep(ctx, driverConfig, target)
+		if err != nil {
+			return steps, best, err
+		}
+		if ctx.Err() != nil {
+			return steps, best, ctx.Err()
+		}
+		steps = append(steps, step)
+		if step.Stop = config.knee(step, best); len(step.Stop) != 0 {
+			break
+		}
+		best = step
+	}
+	return steps, best, nil
+}
+
+// PrintSearch prints the table of search steps and the recommended maximum.
+func PrintSearch(config *SearchConfig, unit Unit, steps []*SearchStep, best *SearchStep) {
+	ErrPrintf("search %v\n", config.Mode)
+	ErrPrintf("    %-6v %-12v %-10v %-12v %-12v %-10v %v\n", "step", config.Mode, "requests", "qps", "p99", "errors", "stop")
+	for i, step := range steps {
+		line := fmt.Sprintf("    %-6v %-12v %-10v %-12.2f %-12v %-10v %v", i+1, step.Target, step.Requests, step.QPS,
+			unit.Format(step.P99), fmt.Sprintf("%.2f%%", step.ErrorRate*100), step.Stop)
+		ErrPrintln(strings.TrimRight(line, " "))
+	}
+	if best == nil {
+		ErrPrintf("no sustainable %v found, the first step already crossed the knee\n", config.Mode)
+		return
+	}
+	ErrPrintf("recommended max %v %v (qps %.2f p99 %v)\n", config.Mode, best.Target, best.QPS, unit.Format(best.P99))
+}
@@ -0,0 +1,43 @@
+package gmeter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchKnee(t *testing.T) {
+	config := &SearchConfig{
+		Mode:          SearchConcurrency,
+		MaxP99:        100 * time.Millisecond,
+		MaxErrorRate:  0.01,
+		MinGain:       0.5,
+		RateTolerance: 0.05,
+	}
+	rate := *config
+	rate.Mode = SearchRate
+	tests := []struct {
+		name   string
+		config *SearchConfig
+		step   SearchStep
+		best   *SearchStep
+		knee   bool
+	}{
+		{name: "first step", config: config, step: SearchStep{Target: 1, QPS: 20, P99: 50 * time.Millisecond}},
+		{name: "linear small target", config: config, step: SearchStep{Target: 5, QPS: 100}, best: &SearchStep{Target: 4, QPS: 80}},
+		{name: "linear large target", config: config, step: SearchStep{Target: 500, QPS: 10000}, best: &SearchStep{Target: 490, QPS: 9800}},
+		{name: "half linear", config: config, step: SearchStep{Target: 200, QPS: 1030}, best: &SearchStep{Target: 190, QPS: 1000}},
+		{name: "flat", config: config, step: SearchStep{Target: 200, QPS: 1010}, best: &SearchStep{Target: 190, QPS: 1000}, knee: true},
+		{name: "drop", config: config, step: SearchStep{Target: 5, QPS: 70}, best: &SearchStep{Target: 4, QPS: 80}, knee: true},
+		{name: "p99", config: config, step: SearchStep{Target: 5, QPS: 100, P99: 200 * time.Millisecond}, best: &SearchStep{Target: 4, QPS: 80}, knee: true},
+		{name: "errors", config: config, step: SearchStep{Target: 5, QPS: 100, ErrorRate: 0.02}, best: &SearchStep{Target: 4, QPS: 80}, knee: true},
+		{name: "rate met", config: &rate, step: SearchStep{Target: 200, QPS: 198}, best: &SearchStep{Target: 100, QPS: 99}},
+		{name: "rate short", config: &rate, step: SearchStep{Target: 200, QPS: 180}, best: &SearchStep{Target: 100, QPS: 99}, knee: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if stop := test.config.knee(&test.step, test.best); (len(stop) != 0) != test.knee {
+				t.Fatalf("got knee %q, want knee %v", stop, test.knee)
+			}
+		})
+	}
+}
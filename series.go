@@ -0,0 +1,144 @@
+package gmeter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type seriesBucket struct {
+	requests int
+	errors   int
+	latency  *Histogram
+}
+
+type SeriesPoint struct {
+	Time     time.Time      `json:"time"`
+	Offset   float64        `json:"offset_s"`
+	Requests int            `json:"requests"`
+	Errors   int            `json:"errors"`
+	RPS      float64        `json:"rps"`
+	Latency  *LatencyReport `json:"latency"`
+}
+
+func (meter *Meter) recordSeries(t time.Time, res *Response, failed bool) {
+	interval := meter.config.SeriesInterval
+	if interval <= 0 {
+		return
+	}
+	key := int64(t.Sub(meter.origin) / interval)
+	bucket, ok := meter.series[key]
+	if !ok {
+		bucket = &seriesBucket{latency: NewHistogram()}
+		meter.series[key] = bucket
+	}
+	bucket.requests += 1
+	if failed {
+		bucket.errors += 1
+	}
+	bucket.latency.Record(int64(res.Cost))
+}
+
+func (meter *Meter) extendSeries(other *Meter) {
+	for key, bucket := range other.series {
+		if mine, ok := meter.series[key]; ok {
+			mine.requests += bucket.requests
+			mine.errors += bucket.errors
+			mine.latency.Merge(bucket.latency)
+		} else {
+			latency := NewHistogram()
+			latency.Merge(bucket.latency)
+			meter.series[key] = &seriesBucket{
+				requests: bucket.requests,
+				errors:   bucket.errors,
+				latency:  latency,
+			}
+		}
+	}
+}
+
+// Series returns one point per interval from the first to the last bucket,
+// including the empty intervals in between. Buckets are aligned to the meter
+// origin, so only meters sharing an origin should be extended into each other.
+func (meter *Meter) Series() []*SeriesPoint {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	interval := meter.config.SeriesInterval
+	if len(meter.series) == 0 {
+		return nil
+	}
+	keys := slices.Sorted(maps.Keys(meter.series))
+	var points []*SeriesPoint
+	empty := &seriesBucket{latency: NewHistogram()}
+	for key := keys[0]; key <= keys[len(keys)-1]; key++ {
+		bucket, ok := meter.series[key]
+		if !ok {
+			bucket = empty
+		}
+		offset := time.Duration(key) * interval
+		points = append(points, &SeriesPoint{
+			Time:     meter.origin.Add(offset),
+			Offset:   offset.Seconds(),
+			Requests: bucket.requests,
+			Errors:   bucket.errors,
+			RPS:      float64(bucket.requests) / interval.Seconds(),
+			Latency:  NewLatencyReport(bucket.latency),
+		})
+	}
+	return points
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeSeriesCsv(w io.Writer, points []*SeriesPoint) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "offset_s", "requests", "errors", "rps",
+		"mean_ms", "p50_ms", "p90_ms", "p99_ms", "max_ms"})
+	for _, point := range points {
+		writer.Write([]string{
+			point.Time.Format(time.RFC3339Nano),
+			formatFloat(point.Offset),
+			strconv.Itoa(point.Requests),
+			strconv.Itoa(point.Errors),
+			formatFloat(point.RPS),
+			formatFloat(point.Latency.Mean),
+			formatFloat(point.Latency.P50),
+			formatFloat(point.Latency.P90),
+			formatFloat(point.Latency.P99),
+			formatFloat(point.Latency.Max),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteSeries writes points to path as csv when it ends with .csv and as json
+// otherwise, "-" meaning json on stdout.
+func WriteSeries(path string, points []*SeriesPoint) error {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if strings.HasSuffix(path, ".csv") {
+		return writeSeriesCsv(w, points)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if points == nil {
+		points = []*SeriesPoint{}
+	}
+	return encoder.Encode(points)
+}
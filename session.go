@@ -0,0 +1,68 @@
+package gmeter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+type SessionConfig struct {
+	CookieJar  bool     `json:"cookie_jar,omitempty"`
+	Cookies    []string `json:"cookies,omitempty"`
+	ResetEvery int      `json:"reset_every,omitempty"`
+}
+
+func (config *SessionConfig) enabled() bool {
+	return config.CookieJar || len(config.Cookies) != 0 || config.ResetEvery > 0
+}
+
+// session is the state one client keeps across its requests: an isolated
+// cookie jar and the variables extracted by its journeys.
+type session struct {
+	config  *SessionConfig
+	cookies []*http.Cookie
+	seeded  map[string]bool
+	vars    map[string]string
+	count   int
+}
+
+func newSession(config *SessionConfig) (*session, error) {
+	s := &session{config: config}
+	for _, item := range config.Cookies {
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || len(name) == 0 {
+			return nil, fmt.Errorf("invalid cookie %q, want name=value", item)
+		}
+		s.cookies = append(s.cookies, &http.Cookie{Name: name, Value: strings.TrimSpace(value), Path: "/"})
+	}
+	return s, nil
+}
+
+func (s *session) reset(client *http.Client) {
+	client.Jar, _ = cookiejar.New(nil)
+	s.seeded = make(map[string]bool)
+	s.vars = make(map[string]string)
+}
+
+// begin starts a request, resetting the session of a new visitor every
+// ResetEvery requests.
+func (s *session) begin(client *http.Client) {
+	if client.Jar == nil || (s.config.ResetEvery > 0 && s.count%s.config.ResetEvery == 0) {
+		s.reset(client)
+	}
+	s.count += 1
+}
+
+// seed puts the configured cookies in the jar the first time a host is
+// visited in the session, for every path of the host so later requests get
+// them too while cookies the server sets afterwards win.
+func (s *session) seed(client *http.Client, u *url.URL) {
+	if len(s.cookies) == 0 || s.seeded[u.Host] {
+		return
+	}
+	client.Jar.SetCookies(u, s.cookies)
+	s.seeded[u.Host] = true
+}
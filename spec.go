@@ -0,0 +1,197 @@
+package gmeter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestSpec is one line of a requests file, a fully described request.
+// Body is either a json string sent as it is or any other json value sent
+// encoded, BodyBase64 carries binary bodies.
+type RequestSpec struct {
+	Method       string          `json:"method,omitempty"`
+	Url          string          `json:"url"`
+	Headers      SpecHeaders     `json:"headers,omitempty"`
+	Body         json.RawMessage `json:"body,omitempty"`
+	BodyBase64   string          `json:"body_base64,omitempty"`
+	Label        string          `json:"label,omitempty"`
+	At           time.Duration   `json:"-"`
+	urlTemplate  *Template
+	bodyTemplate *Template
+}
+
+// SpecHeaders decodes from {"Name": "value"}, {"Name": ["v1", "v2"]} or
+// ["Name: value"] and encodes as an object.
+type SpecHeaders []*Header
+
+func (headers *SpecHeaders) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*headers = parseHeaders(lines)
+		return nil
+	}
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("headers must be an object or a list of \"Name: value\"")
+	}
+	*headers = nil
+	for key, value := range object {
+		switch v := value.(type) {
+		case string:
+			*headers = append(*headers, &Header{Key: key, Value: v})
+		case []any:
+			for _, item := range v {
+				*headers = append(*headers, &Header{Key: key, Value: fmt.Sprint(item)})
+			}
+		default:
+			*headers = append(*headers, &Header{Key: key, Value: fmt.Sprint(v)})
+		}
+	}
+	return nil
+}
+
+func (headers SpecHeaders) MarshalJSON() ([]byte, error) {
+	object := make(map[string]any)
+	for _, header := range headers {
+		switch v := object[header.Key].(type) {
+		case nil:
+			object[header.Key] = header.Value
+		case string:
+			object[header.Key] = []string{v, header.Value}
+		case []string:
+			object[header.Key] = append(v, header.Value)
+		}
+	}
+	return json.Marshal(object)
+}
+
+func (spec *RequestSpec) body() ([]byte, error) {
+	if len(spec.BodyBase64) != 0 {
+		return base64.StdEncoding.DecodeString(spec.BodyBase64)
+	}
+	body := bytes.TrimSpace(spec.Body)
+	if len(body) == 0 || bytes.Equal(body, []byte("null")) {
+		return nil, nil
+	}
+	if body[0] == '"' {
+		var s string
+		if err := json.Unmarshal(body, &s); err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+	return body, nil
+}
+
+// compile compiles the url, text body and header values of the spec that
+// contain template actions in env, reporting whether any renders per client.
+func (spec *RequestSpec) compile(env *templateEnv) (bool, error) {
+	var templates []*Template
+	if isTemplate(spec.Url) {
+		template, err := env.compile(spec.Url)
+		if err != nil {
+			return false, err
+		}
+		spec.urlTemplate = template
+		templates = append(templates, template)
+	}
+	if len(spec.BodyBase64) == 0 {
+		body, err := spec.body()
+		if err != nil {
+			return false, err
+		}
+		if isTemplate(string(body)) {
+			if spec.bodyTemplate, err = env.compile(string(body)); err != nil {
+				return false, err
+			}
+			templates = append(templates, spec.bodyTemplate)
+		}
+	}
+	for _, header := range spec.Headers {
+		if isTemplate(header.Value) {
+			template, err := env.compile(header.Value)
+			if err != nil {
+				return false, err
+			}
+			header.template = template
+			templates = append(templates, template)
+		}
+	}
+	clientScoped := false
+	for _, template := range templates {
+		clientScoped = clientScoped || template.clientScoped
+	}
+	return clientScoped, nil
+}
+
+// build turns the spec into a request, falling back to method and adding the
+// common headers before its own, all templates rendered with ctx.
+func (spec *RequestSpec) build(method string, headers []*Header, ctx *TemplateContext) (*http.Request, error) {
+	if len(spec.Method) != 0 {
+		method = strings.ToUpper(spec.Method)
+	}
+	body, err := spec.body()
+	if err != nil {
+		return nil, err
+	}
+	if spec.bodyTemplate != nil {
+		body = []byte(spec.bodyTemplate.Render(ctx))
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	url := spec.Url
+	if spec.urlTemplate != nil {
+		url = spec.urlTemplate.Render(ctx)
+	}
+	request, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range headers {
+		request.Header.Add(header.Key, header.render(ctx))
+	}
+	for _, header := range spec.Headers {
+		request.Header.Del(header.Key)
+	}
+	for _, header := range spec.Headers {
+		request.Header.Add(header.Key, header.render(ctx))
+	}
+	if host := request.Header.Get("Host"); len(host) != 0 {
+		request.Host = host
+	}
+	if body != nil && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return request, nil
+}
+
+func NewRequestSpecGenerator(path string) (Generator[RequestSpec], error) {
+	generator, err := NewFileGenerator(path)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := NewfilterGenerator(Generator[string](generator), func(line *string) bool {
+		return len(strings.TrimSpace(*line)) != 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewMapGenerator(Generator[string](lines), func(line *string) (*RequestSpec, error) {
+		spec := &RequestSpec{}
+		if err := json.Unmarshal([]byte(*line), spec); err != nil {
+			return nil, err
+		}
+		if len(spec.Url) == 0 {
+			return nil, fmt.Errorf("request spec without url")
+		}
+		return spec, nil
+	}), nil
+}
@@ -0,0 +1,36 @@
+package gmeter
+
+import (
+	"io"
+	"testing"
+)
+
+func TestSpecTemplates(t *testing.T) {
+	config := &RequestGeneratorConfig{
+		Method:       "GET",
+		Headers:      []string{"X-Common: {{.user}}"},
+		RequestsPath: writeTestFile(t, "requests.jsonl", `{"url":"http://a/?u={{.user}}","method":"post","headers":{"X-User":"{{.user}}"},"body":"{\"client\":{{client_id}}}"}`+"\n"),
+		Feeder:       FeederConfig{Path: writeTestFile(t, "users.csv", "user\nu1\n")},
+	}
+	generator, err := NewRequestGenerator(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer generator.Close()
+	request, err := generator.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.prepare == nil {
+		t.Fatal("client_id in the body should build the request per client")
+	}
+	if err := request.prepare(&Client{id: 7}); err != nil {
+		t.Fatal(err)
+	}
+	req := request.Req
+	body, _ := io.ReadAll(req.Body)
+	if req.Method != "POST" || req.URL.String() != "http://a/?u=u1" || string(body) != `{"client":7}` ||
+		req.Header.Get("X-User") != "u1" || req.Header.Get("X-Common") != "u1" {
+		t.Fatalf("got %v %v headers %v body %s", req.Method, req.URL, req.Header, body)
+	}
+}
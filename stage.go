@@ -0,0 +1,119 @@
+package gmeter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ShapeLinear = "linear"
+	ShapeStep   = "step"
+)
+
+type Stage struct {
+	Duration    time.Duration
+	Concurrency int
+	Rate        float64
+	Shape       string
+}
+
+// ParseStage parses "duration:target[:shape]", where target is a concurrency
+// such as "50" or a rate such as "200rps" and shape is linear (default) or step.
+func ParseStage(s string) (Stage, error) {
+	stage := Stage{Shape: ShapeLinear}
+	items := strings.Split(s, ":")
+	if len(items) < 2 || len(items) > 3 {
+		return stage, fmt.Errorf("invalid stage %q, want duration:target[:shape]", s)
+	}
+	duration, err := time.ParseDuration(items[0])
+	if err != nil {
+		return stage, fmt.Errorf("invalid stage %q: %v", s, err)
+	}
+	stage.Duration = duration
+	target := strings.TrimSpace(items[1])
+	if rate, ok := strings.CutSuffix(target, "rps"); ok {
+		if stage.Rate, err = strconv.ParseFloat(rate, 64); err != nil || stage.Rate < 0 {
+			return stage, fmt.Errorf("invalid stage %q: bad rate %q", s, target)
+		}
+	} else if stage.Concurrency, err = strconv.Atoi(target); err != nil || stage.Concurrency < 0 {
+		return stage, fmt.Errorf("invalid stage %q: bad concurrency %q", s, target)
+	}
+	if len(items) == 3 {
+		stage.Shape = items[2]
+	}
+	if stage.Shape != ShapeLinear && stage.Shape != ShapeStep {
+		return stage, fmt.Errorf("invalid stage %q: unknown shape %q", s, stage.Shape)
+	}
+	return stage, nil
+}
+
+type stagePlan struct {
+	stages []Stage
+	rate   bool
+}
+
+func newStagePlan(stages []Stage) (*stagePlan, error) {
+	plan := &stagePlan{stages: stages}
+	concurrency := false
+	for _, stage := range stages {
+		if stage.Duration <= 0 {
+			return nil, fmt.Errorf("stage duration must be positive")
+		}
+		plan.rate = plan.rate || stage.Rate > 0
+		concurrency = concurrency || stage.Concurrency > 0
+	}
+	if plan.rate && concurrency {
+		return nil, fmt.Errorf("stages must not mix rate and concurrency targets")
+	}
+	return plan, nil
+}
+
+func (plan *stagePlan) duration() time.Duration {
+	var duration time.Duration
+	for _, stage := range plan.stages {
+		duration += stage.Duration
+	}
+	return duration
+}
+
+func (stage *Stage) target() float64 {
+	if stage.Rate > 0 {
+		return stage.Rate
+	}
+	return float64(stage.Concurrency)
+}
+
+// at returns the stage index and the interpolated target after elapsed time.
+func (plan *stagePlan) at(elapsed time.Duration) (int, float64) {
+	prev := 0.0
+	for i := range plan.stages {
+		stage := &plan.stages[i]
+		if elapsed < stage.Duration {
+			if stage.Shape == ShapeStep {
+				return i, stage.target()
+			}
+			frac := float64(elapsed) / float64(stage.Duration)
+			return i, prev + (stage.target()-prev)*frac
+		}
+		elapsed -= stage.Duration
+		prev = stage.target()
+	}
+	return len(plan.stages) - 1, prev
+}
+
+func (plan *stagePlan) concurrencyAt(elapsed time.Duration) int {
+	_, target := plan.at(elapsed)
+	return int(math.Round(target))
+}
+
+// clients returns how many clients stage i runs at its widest point.
+func (plan *stagePlan) clients(i int) int {
+	clients := plan.stages[i].Concurrency
+	if i > 0 && plan.stages[i].Shape == ShapeLinear && plan.stages[i-1].Concurrency > clients {
+		clients = plan.stages[i-1].Concurrency
+	}
+	return clients
+}
@@ -0,0 +1,114 @@
+package gmeter
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	ErrorTimeout           = "timeout"
+	ErrorCanceled          = "canceled"
+	ErrorConnectionRefused = "connection_refused"
+	ErrorConnectionReset   = "connection_reset"
+	ErrorTLS               = "tls"
+	ErrorDNS               = "dns"
+	ErrorBodyRead          = "body_read"
+	ErrorExtract           = "extract"
+	ErrorOther             = "other"
+)
+
+func ClassifyError(err error) string {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorConnectionReset
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &certErr),
+		errors.As(err, &unknownAuthErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr),
+		strings.Contains(err.Error(), "tls: "):
+		return ErrorTLS
+	}
+	return ErrorOther
+}
+
+// StatusRule matches status codes against a list such as "5xx,429,400-403".
+type StatusRule struct {
+	text   string
+	ranges [][2]int
+}
+
+func ParseStatusRule(s string) (*StatusRule, error) {
+	rule := &StatusRule{text: s}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		var low, high int
+		var err error
+		if class, ok := strings.CutSuffix(strings.ToLower(item), "xx"); ok {
+			if low, err = strconv.Atoi(class); err == nil {
+				low, high = low*100, low*100+99
+			}
+		} else if from, to, ok := strings.Cut(item, "-"); ok {
+			if low, err = strconv.Atoi(from); err == nil {
+				high, err = strconv.Atoi(to)
+			}
+		} else if low, err = strconv.Atoi(item); err == nil {
+			high = low
+		}
+		if err != nil || low > high {
+			return nil, fmt.Errorf("invalid status %q in %q", item, s)
+		}
+		rule.ranges = append(rule.ranges, [2]int{low, high})
+	}
+	return rule, nil
+}
+
+func (rule *StatusRule) Match(code int) bool {
+	if rule == nil {
+		return false
+	}
+	for _, r := range rule.ranges {
+		if code >= r[0] && code <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *StatusRule) String() string {
+	if rule == nil {
+		return ""
+	}
+	return rule.text
+}
+
+func (rule *StatusRule) MarshalText() ([]byte, error) {
+	return []byte(rule.String()), nil
+}
@@ -0,0 +1,264 @@
+package gmeter
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TemplateContext struct {
+	RequestID int
+	ClientID  int
+	Vars      []map[string]string
+}
+
+func (ctx *TemplateContext) lookup(name string) string {
+	for _, vars := range ctx.Vars {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+type templatePart struct {
+	literal string
+	render  func(ctx *TemplateContext, b *strings.Builder)
+}
+
+// Template is text with {{name args...}} actions, compiled once and rendered
+// per request. Actions are request_id, client_id, rand_int min max,
+// rand_str n, uuid, timestamp [s|ms|us|ns|rfc3339], seq [name], pick a b...
+// and .var for variables such as feeder columns.
+type Template struct {
+	parts        []templatePart
+	clientScoped bool
+	usesVars     bool
+}
+
+// templateEnv holds the state shared by the templates of one run, such as
+// seq counters and the feeder, across the generators it rebuilds.
+type templateEnv struct {
+	mutex    sync.Mutex
+	counters map[string]*atomic.Int64
+	feeder   *Feeder
+}
+
+func newTemplateEnv() *templateEnv {
+	return &templateEnv{counters: make(map[string]*atomic.Int64)}
+}
+
+func (env *templateEnv) counter(name string) *atomic.Int64 {
+	env.mutex.Lock()
+	defer env.mutex.Unlock()
+	counter, ok := env.counters[name]
+	if !ok {
+		counter = &atomic.Int64{}
+		env.counters[name] = counter
+	}
+	return counter
+}
+
+// openFeeder opens the feeder of config on first use and shares it after, so
+// a sequential feeder goes on from its last row when the sources start over.
+func (env *templateEnv) openFeeder(config *FeederConfig) (*Feeder, error) {
+	env.mutex.Lock()
+	defer env.mutex.Unlock()
+	if env.feeder == nil {
+		feeder, err := NewFeeder(config)
+		if err != nil {
+			return nil, err
+		}
+		env.feeder = feeder
+	}
+	return env.feeder, nil
+}
+
+func (env *templateEnv) Close() error {
+	if env.feeder != nil {
+		return env.feeder.Close()
+	}
+	return nil
+}
+
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+func splitTemplateArgs(s string) ([]string, error) {
+	var args []string
+	for s = strings.TrimSpace(s); len(s) != 0; s = strings.TrimSpace(s) {
+		if s[0] == '"' || s[0] == '`' {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, err
+			}
+			arg, _ := strconv.Unquote(quoted)
+			args = append(args, arg)
+			s = s[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		args = append(args, s[:end])
+		s = s[end:]
+	}
+	return args, nil
+}
+
+func (env *templateEnv) compile(s string) (*Template, error) {
+	template := &Template{}
+	for len(s) != 0 {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			template.parts = append(template.parts, templatePart{literal: s})
+			break
+		}
+		if start > 0 {
+			template.parts = append(template.parts, templatePart{literal: s[:start]})
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("template %q: unclosed action", s)
+		}
+		action := s[start+2 : start+end]
+		s = s[start+end+2:]
+		args, err := splitTemplateArgs(action)
+		if err != nil || len(args) == 0 {
+			return nil, fmt.Errorf("template action %q: invalid", action)
+		}
+		render, err := env.action(template, args[0], args[1:])
+		if err != nil {
+			return nil, fmt.Errorf("template action %q: %v", action, err)
+		}
+		template.parts = append(template.parts, templatePart{render: render})
+	}
+	return template, nil
+}
+
+func wantArgs(args []string, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("want %v arguments, got %v", n, len(args))
+	}
+	return nil
+}
+
+func (env *templateEnv) action(template *Template, name string, args []string) (func(*TemplateContext, *strings.Builder), error) {
+	if varName, ok := strings.CutPrefix(name, "."); ok && len(varName) != 0 {
+		template.usesVars = true
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			b.WriteString(ctx.lookup(varName))
+		}, wantArgs(args, 0)
+	}
+	switch name {
+	case "request_id":
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			b.WriteString(strconv.Itoa(ctx.RequestID))
+		}, wantArgs(args, 0)
+	case "client_id":
+		template.clientScoped = true
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			b.WriteString(strconv.Itoa(ctx.ClientID))
+		}, wantArgs(args, 0)
+	case "rand_int":
+		if err := wantArgs(args, 2); err != nil {
+			return nil, err
+		}
+		low, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		high, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || high < low {
+			return nil, fmt.Errorf("invalid range %v %v", args[0], args[1])
+		}
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			b.WriteString(strconv.FormatInt(low+rand.Int64N(high-low+1), 10))
+		}, nil
+	case "rand_str":
+		if err := wantArgs(args, 1); err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid length %v", args[0])
+		}
+		const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			for range n {
+				b.WriteByte(letters[rand.IntN(len(letters))])
+			}
+		}, nil
+	case "uuid":
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			high, low := rand.Uint64(), rand.Uint64()
+			high = high&^0xf000 | 0x4000
+			low = low&^(0xc<<60) | 0x8<<60
+			fmt.Fprintf(b, "%08x-%04x-%04x-%04x-%012x",
+				high>>32, high>>16&0xffff, high&0xffff, low>>48, low&0xffffffffffff)
+		}, wantArgs(args, 0)
+	case "timestamp":
+		format := "s"
+		if len(args) == 1 {
+			format = args[0]
+		} else if len(args) > 1 {
+			return nil, wantArgs(args, 1)
+		}
+		var f func(t time.Time) string
+		switch format {
+		case "s":
+			f = func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+		case "ms":
+			f = func(t time.Time) string { return strconv.FormatInt(t.UnixMilli(), 10) }
+		case "us":
+			f = func(t time.Time) string { return strconv.FormatInt(t.UnixMicro(), 10) }
+		case "ns":
+			f = func(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+		case "rfc3339":
+			f = func(t time.Time) string { return t.Format(time.RFC3339) }
+		default:
+			return nil, fmt.Errorf("unknown timestamp format %v", format)
+		}
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			b.WriteString(f(time.Now()))
+		}, nil
+	case "seq":
+		counterName := ""
+		if len(args) == 1 {
+			counterName = args[0]
+		} else if len(args) > 1 {
+			return nil, wantArgs(args, 1)
+		}
+		counter := env.counter(counterName)
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			b.WriteString(strconv.FormatInt(counter.Add(1), 10))
+		}, nil
+	case "pick":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("want at least one argument")
+		}
+		return func(ctx *TemplateContext, b *strings.Builder) {
+			b.WriteString(args[rand.IntN(len(args))])
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown function %v", name)
+}
+
+func (template *Template) Render(ctx *TemplateContext) string {
+	var b strings.Builder
+	for i := range template.parts {
+		part := &template.parts[i]
+		if part.render != nil {
+			part.render(ctx, &b)
+		} else {
+			b.WriteString(part.literal)
+		}
+	}
+	return b.String()
+}
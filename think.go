@@ -0,0 +1,110 @@
+package gmeter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+const (
+	ThinkFixed   = "fixed"
+	ThinkUniform = "uniform"
+	ThinkNormal  = "normal"
+	ThinkExp     = "exp"
+)
+
+// ThinkTime is the pause a client takes after each request.
+type ThinkTime struct {
+	Kind   string
+	Min    time.Duration
+	Max    time.Duration
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+// ParseThinkTime parses "500ms" (fixed), "100ms-500ms" (uniform),
+// "normal:500ms,100ms" (mean and standard deviation) or "exp:500ms" (mean).
+func ParseThinkTime(s string) (*ThinkTime, error) {
+	think := &ThinkTime{Kind: ThinkFixed}
+	value := s
+	if kind, rest, ok := strings.Cut(s, ":"); ok {
+		think.Kind, value = kind, rest
+	} else if strings.Contains(s, "-") {
+		think.Kind = ThinkUniform
+	}
+	var err error
+	switch think.Kind {
+	case ThinkFixed:
+		think.Mean, err = time.ParseDuration(value)
+	case ThinkUniform:
+		low, high, ok := strings.Cut(value, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid think time %q, want min-max", s)
+		}
+		if think.Min, err = time.ParseDuration(low); err == nil {
+			think.Max, err = time.ParseDuration(high)
+		}
+		if err == nil && think.Max < think.Min {
+			err = fmt.Errorf("max below min")
+		}
+	case ThinkNormal:
+		mean, stdDev, ok := strings.Cut(value, ",")
+		if !ok {
+			return nil, fmt.Errorf("invalid think time %q, want normal:mean,stddev", s)
+		}
+		if think.Mean, err = time.ParseDuration(mean); err == nil {
+			think.StdDev, err = time.ParseDuration(stdDev)
+		}
+	case ThinkExp:
+		think.Mean, err = time.ParseDuration(value)
+	default:
+		return nil, fmt.Errorf("invalid think time %q: unknown distribution %q", s, think.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid think time %q: %v", s, err)
+	}
+	if think.Mean < 0 || think.Min < 0 || think.StdDev < 0 {
+		return nil, fmt.Errorf("invalid think time %q: negative duration", s)
+	}
+	return think, nil
+}
+
+func (think *ThinkTime) Next() time.Duration {
+	switch think.Kind {
+	case ThinkUniform:
+		return think.Min + time.Duration(rand.Int64N(int64(think.Max-think.Min)+1))
+	case ThinkNormal:
+		return time.Duration(math.Max(0, float64(think.Mean)+rand.NormFloat64()*float64(think.StdDev)))
+	case ThinkExp:
+		return time.Duration(rand.ExpFloat64() * float64(think.Mean))
+	}
+	return think.Mean
+}
+
+// pause waits for the think time after a request, or with pacing until the
+// next interval since the request started, returning false once the client
+// should stop.
+func (client *Client) pause(ctx context.Context, start time.Time) bool {
+	var wait time.Duration
+	if client.config.Pacing > 0 {
+		wait = client.config.Pacing - time.Since(start)
+	} else if client.config.Think != nil {
+		wait = client.config.Think.Next()
+	}
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-client.quit:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,151 @@
+package gmeter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrThresholdsFailed = errors.New("thresholds failed")
+
+// Threshold is a run-level criterion such as "p99<250ms", "error_rate<0.5%"
+// or "qps>1000", evaluated on the final report of the driver.
+type Threshold struct {
+	text   string
+	metric string
+	op     string
+	value  float64
+}
+
+var thresholdOps = []string{"<=", ">=", "<", ">"}
+
+func ParseThreshold(s string) (*Threshold, error) {
+	text := strings.ReplaceAll(s, " ", "")
+	for _, op := range thresholdOps {
+		metric, value, ok := strings.Cut(text, op)
+		if !ok {
+			continue
+		}
+		threshold := &Threshold{text: text, metric: strings.ToLower(metric), op: op}
+		if _, err := threshold.measure(&MeterReport{Latency: &LatencyReport{}}); err != nil {
+			return nil, err
+		}
+		if d, err := time.ParseDuration(value); err == nil && strings.ContainsAny(value, "smhµun") {
+			threshold.value = float64(d) / float64(time.Millisecond)
+		} else if percent, ok := strings.CutSuffix(value, "%"); ok {
+			if threshold.value, err = strconv.ParseFloat(percent, 64); err != nil {
+				return nil, fmt.Errorf("invalid threshold %q: %v", s, err)
+			}
+			threshold.value /= 100
+		} else if threshold.value, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid threshold %q: %v", s, err)
+		}
+		return threshold, nil
+	}
+	return nil, fmt.Errorf("invalid threshold %q, want metric<value", s)
+}
+
+func (threshold *Threshold) String() string {
+	return threshold.text
+}
+
+func (threshold *Threshold) MarshalText() ([]byte, error) {
+	return []byte(threshold.text), nil
+}
+
+func (threshold *Threshold) latency() bool {
+	switch threshold.metric {
+	case "requests", "failed", "qps", "rps", "error_rate", "check_failed", "check_rate":
+		return false
+	}
+	return true
+}
+
+// measure returns the metric from report, latencies in milliseconds and rates
+// as fractions.
+func (threshold *Threshold) measure(report *MeterReport) (float64, error) {
+	latency := report.Latency
+	switch threshold.metric {
+	case "requests":
+		return float64(report.Requests), nil
+	case "failed":
+		return float64(report.Failed), nil
+	case "qps", "rps":
+		return report.QPS, nil
+	case "error_rate":
+		return div(report.Failed, int64(report.Requests)), nil
+	case "check_failed":
+		return float64(report.CheckFailed), nil
+	case "check_rate":
+		return div(int64(report.CheckFailed), int64(report.Requests)), nil
+	case "avg", "mean":
+		return latency.Mean, nil
+	case "min":
+		return latency.Min, nil
+	case "max":
+		return latency.Max, nil
+	case "p50":
+		return latency.P50, nil
+	case "p90":
+		return latency.P90, nil
+	case "p95":
+		return latency.P95, nil
+	case "p99":
+		return latency.P99, nil
+	case "p99.9":
+		return latency.P999, nil
+	case "p99.99":
+		return latency.P9999, nil
+	}
+	return 0, fmt.Errorf("unknown threshold metric %q", threshold.metric)
+}
+
+func (threshold *Threshold) Evaluate(report *MeterReport) (float64, bool) {
+	actual, _ := threshold.measure(report)
+	switch threshold.op {
+	case "<":
+		return actual, actual < threshold.value
+	case "<=":
+		return actual, actual <= threshold.value
+	case ">":
+		return actual, actual > threshold.value
+	}
+	return actual, actual >= threshold.value
+}
+
+func (threshold *Threshold) format(value float64) string {
+	if threshold.latency() {
+		return UnitAuto.Format(time.Duration(value * float64(time.Millisecond)))
+	}
+	switch threshold.metric {
+	case "error_rate", "check_rate":
+		return strconv.FormatFloat(value*100, 'f', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// CheckThresholds prints a line per threshold and returns ErrThresholdsFailed
+// if any of them is missed.
+func CheckThresholds(thresholds []*Threshold, report *MeterReport) error {
+	if len(thresholds) == 0 {
+		return nil
+	}
+	failed := 0
+	ErrPrintln("thresholds:")
+	for _, threshold := range thresholds {
+		actual, ok := threshold.Evaluate(report)
+		result := "ok"
+		if !ok {
+			result = "FAILED"
+			failed += 1
+		}
+		ErrPrintf("    %v actual %v %v\n", threshold, threshold.format(actual), result)
+	}
+	ErrPrintln("")
+	if failed != 0 {
+		return fmt.Errorf("%w: %v of %v", ErrThresholdsFailed, failed, len(thresholds))
+	}
+	return nil
+}
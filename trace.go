@@ -0,0 +1,114 @@
+package gmeter
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+var phaseNames = []string{"dns", "connect", "tls", "ttfb", "transfer"}
+
+type Timing struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration
+	Transfer time.Duration
+}
+
+func (timing *Timing) phases() []time.Duration {
+	return []time.Duration{timing.DNS, timing.Connect, timing.TLS, timing.TTFB, timing.Transfer}
+}
+
+type tracer struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wrote        time.Time
+	firstByte    time.Time
+	timing       Timing
+}
+
+func newTracer(start time.Time) *tracer {
+	return &tracer{start: start}
+}
+
+func (tracer *tracer) since(start time.Time) time.Duration {
+	if start.IsZero() {
+		return 0
+	}
+	return time.Since(start)
+}
+
+func (tracer *tracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			if info.Err == nil {
+				tracer.timing.DNS = tracer.since(tracer.dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			if tracer.connectStart.IsZero() {
+				tracer.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			if err == nil {
+				tracer.timing.Connect = tracer.since(tracer.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			if err == nil {
+				tracer.timing.TLS = tracer.since(tracer.tlsStart)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.wrote = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			tracer.firstByte = time.Now()
+		},
+	}
+}
+
+// finish completes the timing once the body has been read at end. TTFB runs
+// from the request being written, so it leaves out dns, connect and tls.
+func (tracer *tracer) finish(end time.Time) Timing {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	timing := tracer.timing
+	if !tracer.firstByte.IsZero() {
+		wrote := tracer.wrote
+		if wrote.IsZero() {
+			wrote = tracer.start
+		}
+		timing.TTFB = tracer.firstByte.Sub(wrote)
+		timing.Transfer = end.Sub(tracer.firstByte)
+	}
+	return timing
+}
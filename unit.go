@@ -0,0 +1,64 @@
+package gmeter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+type Unit string
+
+const (
+	UnitAuto        Unit = "auto"
+	UnitMicrosecond Unit = "us"
+	UnitMillisecond Unit = "ms"
+	UnitSecond      Unit = "s"
+)
+
+func ParseUnit(s string) (Unit, error) {
+	switch s {
+	case "", "auto":
+		return UnitAuto, nil
+	case "us", "µs":
+		return UnitMicrosecond, nil
+	case "ms":
+		return UnitMillisecond, nil
+	case "s":
+		return UnitSecond, nil
+	}
+	return UnitAuto, fmt.Errorf("unknown unit %q, want auto, us, ms or s", s)
+}
+
+func (unit Unit) pick(d time.Duration) Unit {
+	if unit != UnitAuto && unit != "" {
+		return unit
+	}
+	if d < time.Millisecond {
+		return UnitMicrosecond
+	} else if d < time.Second {
+		return UnitMillisecond
+	}
+	return UnitSecond
+}
+
+// Value converts d to a number in unit, resolving auto by the size of d.
+func (unit Unit) Value(d time.Duration) (float64, Unit) {
+	unit = unit.pick(d)
+	switch unit {
+	case UnitMicrosecond:
+		return float64(d) / float64(time.Microsecond), unit
+	case UnitSecond:
+		return d.Seconds(), unit
+	}
+	return float64(d) / float64(time.Millisecond), UnitMillisecond
+}
+
+func (unit Unit) Format(d time.Duration) string {
+	value, unit := unit.Value(d)
+	symbol := string(unit)
+	if unit == UnitMicrosecond {
+		symbol = "µs"
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64) + symbol
+}
@@ -1,9 +1,7 @@
 package gmeter
 
 import (
-	"errors"
 	"fmt"
-	"math"
 	"os"
 	"strings"
 )
@@ -33,19 +31,21 @@ func GainError(errs []error) error {
 	if len(errs) == 1 {
 		return errs[0]
 	}
+	return joinedError(errs)
+}
+
+type joinedError []error
+
+func (errs joinedError) Error() string {
 	var strs []string
 	for _, err := range errs {
 		strs = append(strs, err.Error())
 	}
-	return errors.New(strings.Join(strs, " "))
+	return strings.Join(strs, " ")
 }
 
-func sum(nums []int64) int64 {
-	sum := int64(0)
-	for _, num := range nums {
-		sum += num
-	}
-	return sum
+func (errs joinedError) Unwrap() []error {
+	return errs
 }
 
 func div(num1 int64, num2 int64) float64 {
@@ -55,36 +55,7 @@ func div(num1 int64, num2 int64) float64 {
 	return float64(num1) / float64(num2)
 }
 
-func max(nums ...int64) int64 {
-	m := int64(math.MinInt64)
-	for _, num := range nums {
-		if num > m {
-			m = num
-		}
-	}
-	return m
-}
-
-func maxWithDefault(defaultNum int64, nums ...int64) int64 {
-	if len(nums) == 0 {
-		return defaultNum
-	}
-	return max(nums...)
-}
-
-func minWithDefault(defaultNum int64, nums ...int64) int64 {
-	if len(nums) == 0 {
-		return defaultNum
-	}
-	return min(nums...)
-}
-
-func min(nums ...int64) int64 {
-	m := int64(math.MaxInt64)
-	for _, num := range nums {
-		if num < m {
-			m = num
-		}
-	}
-	return m
+func isTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
 }